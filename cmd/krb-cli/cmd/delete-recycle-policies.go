@@ -0,0 +1,54 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/spf13/cobra"
+)
+
+// deleteRecyclePoliciesCmd represents the delete recycle policies command
+var deleteRecyclePoliciesCmd = &cobra.Command{
+	Use:     "recyclepolicies",
+	Aliases: []string{"rp", "recyclepolicy"},
+	Short:   "Delete recycle policies",
+	Long:    `Delete recycle policies. Resources deleted afterwards are no longer recycled, existing RecycleItems are kept.`,
+	Example: `
+# Delete RecyclePolicy with names foo and bar
+krb-cli delete rp foo bar
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDeleteRecyclePolicies(args)
+	},
+	ValidArgsFunction: completion.RecyclePolicy,
+}
+
+func init() {
+	deleteCmd.AddCommand(deleteRecyclePoliciesCmd)
+}
+
+func runDeleteRecyclePolicies(args []string) {
+	if len(args) == 0 {
+		tlog.Panicf("✗ please specify recycle policies to delete.")
+	}
+
+	for _, name := range args {
+		deleteRecyclePolicy(name)
+	}
+}
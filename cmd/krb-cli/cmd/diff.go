@@ -0,0 +1,136 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
+	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
+	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type DiffFlags struct {
+	SanitizeRules []string
+	Context       int
+}
+
+var diffFlags DiffFlags
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff",
+	Short: "Diff recycled resource objects from RecycleItem against the live objects",
+	Example: `
+# Show what restoring RecycleItem foo would change in the cluster
+krb-cli diff foo
+
+# Diff RecycleItems foo and bar ignoring the replicas of deployments
+krb-cli diff foo bar --sanitize Deployment.apps:spec.replicas
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runDiff(args)
+	},
+	ValidArgsFunction: completion.RecycleItem,
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringArrayVarP(&diffFlags.SanitizeRules, "sanitize", "", nil, "Remove the field from both objects of the kind before diffing them, in the form of Kind.group:field such as Deployment.apps:spec.paused")
+	diffCmd.Flags().IntVarP(&diffFlags.Context, "context", "", 3, "Lines of context around the changes")
+}
+
+func runDiff(args []string) {
+	if len(args) == 0 {
+		tlog.Panicf("✗ please specify recycle items to diff.")
+	}
+
+	registerSanitizeRules(diffFlags.SanitizeRules)
+
+	for _, recycleItemName := range args {
+		recycleItem, err := krbclient.RecycleItem().Get(context.Background(), recycleItemName, client.GetOptions{})
+		if err != nil {
+			tlog.Printf("✗ failed to get RecycleItem [%s]: %v, ignored.", recycleItemName, err)
+			continue
+		}
+		diffRecycleItem(recycleItem)
+	}
+}
+
+// diffRecycleItem prints the unified diff from the live object to the
+// sanitized recycled object, both rendered as YAML.
+func diffRecycleItem(recycleItem *api.RecycleItem) {
+	objKey := recycleItem.Object.GroupResource().String() + ": " + recycleItem.Object.Key()
+
+	if err := krbclient.LoadPayload(context.Background(), recycleItem); err != nil {
+		tlog.Printf("✗ failed to load payload of RecycleItem [%s]: %v, ignored.", recycleItem.Name, err)
+		return
+	}
+	recycledObj, err := recycleItem.Object.Unstructured()
+	if err != nil {
+		tlog.Printf("✗ failed to get unstructured object from RecycleItem [%s]: %v, ignored.", recycleItem.Name, err)
+		return
+	}
+	recycledYAML, err := objectYAML(recycledObj)
+	if err != nil {
+		tlog.Printf("✗ failed to render recycled resource object [%s] in YAML format: %v, ignored.", objKey, err)
+		return
+	}
+
+	var liveYAML string
+	fromName := "live/" + recycleItem.Object.Key()
+	liveObj, err := kube.DynamicClient().Resource(recycleItem.Object.GroupVersionResource()).Namespace(recycleItem.Object.Namespace).Get(context.Background(), recycleItem.Object.Name, metav1.GetOptions{})
+	switch {
+	case k8serrors.IsNotFound(err):
+		fromName = "/dev/null"
+	case err != nil:
+		tlog.Printf("✗ failed to get live resource object [%s]: %v, ignored.", objKey, err)
+		return
+	default:
+		api.Sanitize(liveObj)
+		if liveYAML, err = objectYAML(liveObj); err != nil {
+			tlog.Printf("✗ failed to render live resource object [%s] in YAML format: %v, ignored.", objKey, err)
+			return
+		}
+	}
+
+	diff := util.UnifiedDiff(fromName, "recycled/"+recycleItem.Name, liveYAML, recycledYAML, diffFlags.Context)
+	if diff == "" {
+		tlog.Printf("» recycled resource object [%s] is identical to the live one.", objKey)
+		return
+	}
+	tlog.Print(diff)
+}
+
+// objectYAML renders the object in YAML the same way RecycledObject does.
+func objectYAML(obj *unstructured.Unstructured) (string, error) {
+	raw, err := obj.MarshalJSON()
+	if err != nil {
+		return "", err
+	}
+	return (&api.RecycledObject{Raw: raw}).YAML()
+}
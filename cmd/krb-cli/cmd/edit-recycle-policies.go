@@ -0,0 +1,93 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"slices"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
+	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type EditRecyclePolicyFlags struct {
+	AddNamespaces    []string
+	RemoveNamespaces []string
+}
+
+var editRecyclePolicyFlags EditRecyclePolicyFlags
+
+// editRecyclePolicyCmd represents the edit recycle policy command
+var editRecyclePolicyCmd = &cobra.Command{
+	Use:     "recyclepolicy",
+	Aliases: []string{"rp", "recyclepolicies"},
+	Short:   "Edit the target namespaces of a recycle policy",
+	Example: `
+# Also recycle in namespace staging
+krb-cli edit rp foo --add-namespaces staging
+
+# Stop recycling in namespace dev
+krb-cli edit rp foo --remove-namespaces dev
+`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runEditRecyclePolicy(args[0])
+	},
+	ValidArgsFunction: completion.RecyclePolicy,
+}
+
+func init() {
+	editCmd.AddCommand(editRecyclePolicyCmd)
+
+	editRecyclePolicyCmd.Flags().StringSliceVarP(&editRecyclePolicyFlags.AddNamespaces, "add-namespaces", "", []string{}, "Add the namespaces to the target namespaces of the RecyclePolicy")
+	editRecyclePolicyCmd.Flags().StringSliceVarP(&editRecyclePolicyFlags.RemoveNamespaces, "remove-namespaces", "", []string{}, "Remove the namespaces from the target namespaces of the RecyclePolicy")
+	editRecyclePolicyCmd.RegisterFlagCompletionFunc("remove-namespaces", completion.RecyclePolicyNamespace)
+}
+
+func runEditRecyclePolicy(name string) {
+	if len(editRecyclePolicyFlags.AddNamespaces) == 0 && len(editRecyclePolicyFlags.RemoveNamespaces) == 0 {
+		tlog.Panicf("✗ please specify --add-namespaces or --remove-namespaces.")
+	}
+
+	policy, err := krbclient.RecyclePolicy().Get(context.Background(), name, client.GetOptions{})
+	if err != nil {
+		tlog.Panicf("✗ failed to get RecyclePolicy [%s]: %v", name, err)
+	}
+	if len(policy.Target.Namespaces) == 0 {
+		tlog.Panicf("✗ recycle policy [%s] targets all namespaces, its namespaces can't be edited.", name)
+	}
+
+	for _, namespace := range editRecyclePolicyFlags.AddNamespaces {
+		if api.BuiltinExclusions.ExcludesNamespace(namespace) {
+			tlog.Panicf("✗ namespace %s is %v.", namespace, api.ErrExcluded)
+		}
+	}
+
+	namespaces := slices.Concat(policy.Target.Namespaces, editRecyclePolicyFlags.AddNamespaces)
+	namespaces = slices.DeleteFunc(namespaces, func(ns string) bool {
+		return slices.Contains(editRecyclePolicyFlags.RemoveNamespaces, ns)
+	})
+	if len(namespaces) == 0 {
+		// No namespaces would target all namespaces.
+		tlog.Panicf("✗ can't remove all namespaces from recycle policy [%s], use `krb-cli delete rp %s` to stop recycling.", name, name)
+	}
+	updateRecyclePolicyNamespaces(policy, namespaces)
+}
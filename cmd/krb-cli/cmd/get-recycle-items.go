@@ -20,7 +20,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/jedib0t/go-pretty/v6/table"
@@ -62,6 +64,9 @@ krb-cli get ri --object-resource deployments
 
 # Get RecycleItems recycled from dev namespace
 krb-cli get ri --object-namespace dev
+
+# Get RecycleItems with who deleted the objects and how
+krb-cli get ri -o wide
 `,
 	Run: func(cmd *cobra.Command, args []string) {
 		runGetRecycleItems(args)
@@ -74,12 +79,12 @@ func init() {
 
 	getRecycleItemCmd.Flags().StringVarP(&getRecycleItemFlags.ObjectResource, "object-resource", "", "", "List recycled resource objects filtered by the specified object resource")
 	getRecycleItemCmd.Flags().StringVarP(&getRecycleItemFlags.ObjectNamespace, "object-namespace", "", "", "List recycled resource objects filtered by the specified object namespace")
-	getRecycleItemCmd.Flags().StringVarP(&getRecycleItemFlags.OutputFormat, "output", "o", "", "Output format. One of: json|yaml")
+	getRecycleItemCmd.Flags().StringVarP(&getRecycleItemFlags.OutputFormat, "output", "o", "", "Output format. One of: json|yaml|wide")
 
 	getRecycleItemCmd.RegisterFlagCompletionFunc("object-resource", completion.RecycleItemGroupResource)
 	getRecycleItemCmd.RegisterFlagCompletionFunc("object-namespace", completion.RecycleItemNamespace)
 	getRecycleItemCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+		return []string{"json", "yaml", "wide"}, cobra.ShellCompDirectiveNoFileComp
 	})
 }
 
@@ -96,20 +101,8 @@ func runGetRecycleItems(args []string) {
 			result.Items = append(result.Items, *obj)
 		}
 	} else {
-		labelSet := labels.Set{}
-		if getRecycleItemFlags.ObjectNamespace != "" {
-			labelSet["krb.ketches.cn/object-namespace"] = getRecycleItemFlags.ObjectNamespace
-		}
-		if getRecycleItemFlags.ObjectResource != "" {
-			if gvr, err := kube.GetPreferredGroupVersionResourceFor(getRecycleItemFlags.ObjectResource); err != nil {
-				tlog.Panicf("✗ failed to get preferred group version resource: %v", err)
-			} else {
-				labelSet["krb.ketches.cn/object-gr"] = gvr.GroupResource().String()
-			}
-		}
-
 		list, err := krbclient.RecycleItem().List(context.Background(), client.ListOptions{
-			LabelSelector: labels.SelectorFromSet(labelSet),
+			LabelSelector: recycleItemSelector(getRecycleItemFlags.ObjectResource, getRecycleItemFlags.ObjectNamespace),
 		})
 		if err != nil {
 			tlog.Panicf("✗ failed to list RecycleItem: %v", err)
@@ -144,6 +137,32 @@ func runGetRecycleItems(args []string) {
 			tlog.Panicf("failed to indent recycle items json: %v", err)
 		}
 		tlog.Println(output.String())
+	case "wide":
+		t := table.NewWriter()
+		t.SetOutputMirror(os.Stdout)
+		t.AppendHeader(table.Row{"Name", "Object Key", "Object APIVersion", "Object Kind", "Deleted By", "Groups", "Service Account", "Propagation Policy", "Grace Period", "Admission UID", "Deleted At", "Age"})
+		for _, obj := range result.Items {
+			deletion := obj.Deletion
+			if deletion == nil {
+				deletion = &api.DeletionInfo{}
+			}
+			t.AppendRow(table.Row{
+				obj.Name,
+				obj.Object.Key(),
+				obj.Object.GroupVersion().String(),
+				obj.Object.Kind,
+				deletion.Username,
+				strings.Join(deletion.Groups, ","),
+				deletion.ServiceAccount,
+				deletionPropagationPolicy(deletion),
+				deletionGracePeriod(deletion),
+				deletion.AdmissionUID,
+				deletionTime(deletion),
+				duration.HumanDuration(time.Since(obj.CreationTimestamp.Time)),
+			})
+		}
+		t.SetStyle(KrbTableStyle)
+		t.Render()
 	default:
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
@@ -157,3 +176,41 @@ func runGetRecycleItems(args []string) {
 		t.Render()
 	}
 }
+
+// recycleItemSelector selects RecycleItems by the resource and namespace of
+// their recycled objects, either may be empty.
+func recycleItemSelector(objectResource, objectNamespace string) labels.Selector {
+	labelSet := labels.Set{}
+	if objectNamespace != "" {
+		labelSet[api.ObjectNamespaceLabel] = objectNamespace
+	}
+	if objectResource != "" {
+		if gvr, err := kube.GetPreferredGroupVersionResourceFor(objectResource); err != nil {
+			tlog.Panicf("✗ failed to get preferred group version resource: %v", err)
+		} else {
+			labelSet[api.ObjectGroupResourceLabel] = gvr.GroupResource().String()
+		}
+	}
+	return labels.SelectorFromSet(labelSet)
+}
+
+func deletionPropagationPolicy(deletion *api.DeletionInfo) string {
+	if deletion.PropagationPolicy == nil {
+		return ""
+	}
+	return string(*deletion.PropagationPolicy)
+}
+
+func deletionGracePeriod(deletion *api.DeletionInfo) string {
+	if deletion.GracePeriodSeconds == nil {
+		return ""
+	}
+	return fmt.Sprintf("%ds", *deletion.GracePeriodSeconds)
+}
+
+func deletionTime(deletion *api.DeletionInfo) string {
+	if deletion.DeletedAt.IsZero() {
+		return ""
+	}
+	return deletion.DeletedAt.Format(time.RFC3339)
+}
@@ -31,6 +31,7 @@ import (
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/duration"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -101,12 +102,14 @@ func runGetRecyclePolicies(args []string) {
 			if gvr, err := kube.GetPreferredGroupVersionResourceFor(getRecyclePoliciesFlags.TargetResource); err != nil {
 				tlog.Panicf("✗ failed to get preferred group version resource: %v", err)
 			} else {
-				labelSet["krb.ketches.cn/target-gr"] = gvr.GroupResource().String()
+				labelSet[api.TargetGroupResourceLabel] = gvr.GroupResource().String()
 			}
 		}
+		if getRecyclePoliciesFlags.TargetNamespace != "" {
+			labelSet[api.TargetNamespaceLabelPrefix+getRecyclePoliciesFlags.TargetNamespace] = "true"
+		}
 		list, err := krbclient.RecyclePolicy().List(context.Background(), client.ListOptions{
 			LabelSelector: labels.SelectorFromSet(labelSet),
-			Namespace:     getRecyclePoliciesFlags.TargetNamespace,
 		})
 		if err != nil {
 			tlog.Panicf("✗ failed to list RecyclePolicy: %v", err)
@@ -144,10 +147,14 @@ func runGetRecyclePolicies(args []string) {
 	default:
 		t := table.NewWriter()
 		t.SetOutputMirror(os.Stdout)
-		t.AppendHeader(table.Row{"Name", "Target GR", "Target Namespaces", "Age"})
+		t.AppendHeader(table.Row{"Name", "Target GR", "Target Namespaces", "Ready", "Recycled", "Restored", "Age"})
 
 		for _, obj := range result.Items {
-			t.AppendRow(table.Row{obj.Name, obj.Target.GroupResource().String(), strings.Join(obj.Target.Namespaces, ","), duration.HumanDuration(time.Since(obj.CreationTimestamp.Time))}, table.RowConfig{
+			ready := "Unknown"
+			if condition := meta.FindStatusCondition(obj.Status.Conditions, api.RecyclePolicyConditionReady); condition != nil {
+				ready = string(condition.Status)
+			}
+			t.AppendRow(table.Row{obj.Name, obj.Target.String(), strings.Join(obj.Target.Namespaces, ","), ready, obj.Status.RecycledCount, obj.Status.RestoredCount, duration.HumanDuration(time.Since(obj.CreationTimestamp.Time))}, table.RowConfig{
 				AutoMerge: true,
 			})
 		}
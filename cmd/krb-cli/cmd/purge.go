@@ -0,0 +1,187 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
+	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type PurgeFlags struct {
+	ObjectResource  string
+	ObjectNamespace string
+	OlderThan       time.Duration
+	All             bool
+	Yes             bool
+}
+
+var purgeFlags PurgeFlags
+
+const purgeExample = `
+# Purge RecycleItems with names foo and bar
+krb-cli purge foo bar
+
+# Purge RecycleItems recycled from deployments in dev namespace
+krb-cli purge --object-resource deployments --object-namespace dev
+
+# Purge RecycleItems recycled more than 3 days ago without confirmation
+krb-cli purge --older-than 72h --yes
+
+# Purge all RecycleItems
+krb-cli purge --all
+
+# Same as krb-cli purge foo bar
+krb-cli delete ri foo bar
+`
+
+// purgeCmd represents the purge command
+var purgeCmd = &cobra.Command{
+	Use:     "purge",
+	Short:   "Permanently delete RecycleItems",
+	Example: purgeExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPurge(args)
+	},
+	ValidArgsFunction: completion.RecycleItem,
+}
+
+// deleteRecycleItemsCmd represents the delete recycle items command
+var deleteRecycleItemsCmd = &cobra.Command{
+	Use:     "recycleitems",
+	Aliases: []string{"ri", "recycleitem"},
+	Short:   "Permanently delete RecycleItems, same as purge",
+	Example: purgeExample,
+	Run: func(cmd *cobra.Command, args []string) {
+		runPurge(args)
+	},
+	ValidArgsFunction: completion.RecycleItem,
+}
+
+func init() {
+	rootCmd.AddCommand(purgeCmd)
+	deleteCmd.AddCommand(deleteRecycleItemsCmd)
+
+	for _, cmd := range []*cobra.Command{purgeCmd, deleteRecycleItemsCmd} {
+		cmd.Flags().StringVarP(&purgeFlags.ObjectResource, "object-resource", "", "", "Purge RecycleItems filtered by the specified object resource")
+		cmd.Flags().StringVarP(&purgeFlags.ObjectNamespace, "object-namespace", "", "", "Purge RecycleItems filtered by the specified object namespace")
+		cmd.Flags().DurationVarP(&purgeFlags.OlderThan, "older-than", "", 0, "Purge RecycleItems recycled longer ago than the specified duration, such as 72h")
+		cmd.Flags().BoolVarP(&purgeFlags.All, "all", "", false, "Purge all RecycleItems matching the filters, or all RecycleItems without filters")
+		cmd.Flags().BoolVarP(&purgeFlags.Yes, "yes", "y", false, "Purge without asking for confirmation")
+
+		cmd.RegisterFlagCompletionFunc("object-resource", completion.RecycleItemGroupResource)
+		cmd.RegisterFlagCompletionFunc("object-namespace", completion.RecycleItemNamespace)
+	}
+}
+
+func runPurge(args []string) {
+	filtered := purgeFlags.ObjectResource != "" || purgeFlags.ObjectNamespace != "" || purgeFlags.OlderThan > 0
+	if len(args) > 0 && purgeFlags.All {
+		tlog.Panicf("✗ recycle item names can't be used with --all.")
+	}
+	if len(args) == 0 && !filtered && !purgeFlags.All {
+		tlog.Panicf("✗ please specify recycle items to purge, filters or --all.")
+	}
+
+	var candidates []api.RecycleItem
+	if len(args) > 0 {
+		for _, name := range args {
+			recycleItem, err := krbclient.RecycleItem().Get(context.Background(), name, client.GetOptions{})
+			if err != nil {
+				tlog.Printf("✗ failed to get RecycleItem [%s]: %v, ignored.", name, err)
+				continue
+			}
+			candidates = append(candidates, *recycleItem)
+		}
+	} else {
+		list, err := krbclient.RecycleItem().List(context.Background(), client.ListOptions{
+			LabelSelector: recycleItemSelector(purgeFlags.ObjectResource, purgeFlags.ObjectNamespace),
+		})
+		if err != nil {
+			tlog.Panicf("✗ failed to list RecycleItem: %v", err)
+		}
+		candidates = list.Items
+	}
+
+	var recycleItems []api.RecycleItem
+	for _, recycleItem := range candidates {
+		if purgeMatches(&recycleItem, time.Now()) {
+			recycleItems = append(recycleItems, recycleItem)
+		}
+	}
+	if len(recycleItems) == 0 {
+		tlog.Println("No recycle items to purge.")
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Name", "Object Key", "Object APIVersion", "Object Kind", "Age"})
+	for _, obj := range recycleItems {
+		t.AppendRow(table.Row{obj.Name, obj.Object.Key(), obj.Object.GroupVersion().String(), obj.Object.Kind, duration.HumanDuration(time.Since(obj.CreationTimestamp.Time))})
+	}
+	t.SetStyle(KrbTableStyle)
+	t.Render()
+	tlog.Println("")
+
+	if !purgeFlags.Yes && !confirm(fmt.Sprintf("Permanently delete %d recycle items? The recycled resource objects can't be restored afterwards.", len(recycleItems))) {
+		tlog.Println("Purge cancelled.")
+		return
+	}
+
+	var purged int
+	for _, recycleItem := range recycleItems {
+		if err := krbclient.RecycleItem().Delete(context.Background(), recycleItem.Name, client.DeleteOptions{}); err != nil {
+			tlog.Printf("✗ failed to purge RecycleItem [%s]: %v", recycleItem.Name, err)
+			continue
+		}
+		purged++
+	}
+	tlog.Printf("✓ purged %d of %d recycle items.", purged, len(recycleItems))
+}
+
+// purgeMatches reports whether the RecycleItem passes the filters of the purge
+// flags, the label filters are checked again for RecycleItems given by name.
+func purgeMatches(recycleItem *api.RecycleItem, now time.Time) bool {
+	if purgeFlags.ObjectNamespace != "" && recycleItem.Object.Namespace != purgeFlags.ObjectNamespace {
+		return false
+	}
+	if purgeFlags.ObjectResource != "" && !recycleItemSelector(purgeFlags.ObjectResource, "").Matches(labels.Set(recycleItem.Labels)) {
+		return false
+	}
+	if purgeFlags.OlderThan > 0 {
+		recycledAt, err := recycleItem.RecycledAt()
+		if err != nil {
+			recycledAt = recycleItem.CreationTimestamp.Time
+		}
+		if now.Sub(recycledAt) < purgeFlags.OlderThan {
+			return false
+		}
+	}
+	return true
+}
@@ -18,18 +18,31 @@ package cmd
 
 import (
 	"context"
+	"time"
 
 	"github.com/ketches/kube-recycle-bin/internal/api"
 	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
 	"github.com/ketches/kube-recycle-bin/internal/completion"
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
 	"github.com/spf13/cobra"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type RecycleFlags struct {
-	TargetNamespaces []string
+	Name              string
+	AllInGroup        []string
+	Category          string
+	TargetNamespaces  []string
+	Retention         string
+	DeletionPolicy    string
+	FailurePolicy     string
+	TimeoutSeconds    int32
+	Selector          string
+	NamespaceSelector string
 }
 
 var recycleFlags RecycleFlags
@@ -44,8 +57,31 @@ krb-cli recycle deployments -n dev,prod
 
 # Recycle service in all namespaces
 krb-cli recycle services
+
+# Recycle configmaps and keep the recycled items for 7 days
+krb-cli recycle configmaps --retention 168h
+
+# Recycle pods and let deletions through unrecycled while krb-webhook is unavailable
+krb-cli recycle pods --failure-policy Ignore --timeout-seconds 2
+
+# Recycle jobs and purge the recycled items once the RecyclePolicy is deleted
+krb-cli recycle jobs --deletion-policy Purge
+
+# Recycle deployments labelled team=payments, except those labelled krb.ketches.cn/skip=true
+krb-cli recycle deployments -l team=payments,krb.ketches.cn/skip!=true
+
+# Recycle secrets in namespaces labelled env=prod
+krb-cli recycle secrets --namespace-selector env=prod
+
+# Recycle deployments with a RecyclePolicy named recycle-deployments
+krb-cli recycle deployments --name recycle-deployments
+
+# Recycle all resources of the apps group and services with a single RecyclePolicy
+krb-cli recycle services --all-in-group apps
+
+# Recycle all resources in the category all, such as pods, services and deployments
+krb-cli recycle --category all -n dev
 `,
-	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		runRecycle(args)
 	},
@@ -55,14 +91,81 @@ krb-cli recycle services
 func init() {
 	rootCmd.AddCommand(recycleCmd)
 
+	recycleCmd.Flags().StringVarP(&recycleFlags.Name, "name", "", "", "Create the RecyclePolicy with the specified name instead of a random one, only for a single resource")
+	recycleCmd.Flags().StringSliceVarP(&recycleFlags.AllInGroup, "all-in-group", "", []string{}, "Recycle all resources of the groups, core for the core group. The resources and groups are recycled by a single RecyclePolicy")
+	recycleCmd.Flags().StringVarP(&recycleFlags.Category, "category", "", "", "Recycle all resources in the category, such as all. The resources and category are recycled by a single RecyclePolicy")
 	recycleCmd.Flags().StringSliceVarP(&recycleFlags.TargetNamespaces, "target-namespaces", "n", []string{}, "Create a RecyclePolicy with specific target namespaces")
+	recycleCmd.Flags().StringVarP(&recycleFlags.Retention, "retention", "", "", "Keep recycled items for the specified duration such as 168h, 0s keeps them forever (defaults to the krb-controller default retention)")
+	recycleCmd.Flags().StringVarP(&recycleFlags.DeletionPolicy, "deletion-policy", "", string(api.DeletionPolicyKeep), "What happens to the recycled items when the RecyclePolicy is deleted. One of: Keep|Purge")
+	recycleCmd.Flags().StringVarP(&recycleFlags.FailurePolicy, "failure-policy", "", "", "Failure policy of the webhook while krb-webhook is unavailable, defaults to the krb-controller default. One of: Fail|Ignore")
+	recycleCmd.Flags().Int32VarP(&recycleFlags.TimeoutSeconds, "timeout-seconds", "", 0, "Timeout of the webhook in seconds between 1 and 30, defaults to the krb-controller default")
+	recycleCmd.Flags().StringVarP(&recycleFlags.Selector, "selector", "l", "", "Create a RecyclePolicy only for objects matching the label selector, such as key1=value1,key2!=value2")
+	recycleCmd.Flags().StringVarP(&recycleFlags.NamespaceSelector, "namespace-selector", "", "", "Create a RecyclePolicy only for objects in namespaces matching the label selector, such as env=prod")
 }
 
 func runRecycle(args []string) {
-	if len(args) == 0 {
-		tlog.Panicf("✗ please specify a resource to recycle.")
+	// Resources of a group or category are recycled by a single policy,
+	// together with the resources given as arguments.
+	single := len(recycleFlags.AllInGroup) > 0 || recycleFlags.Category != ""
+	if len(args) == 0 && !single {
+		tlog.Panicf("✗ please specify a resource, --all-in-group or --category to recycle.")
+	}
+	if recycleFlags.Name != "" && len(args) > 1 && !single {
+		tlog.Panicf("✗ --name can only be used to recycle a single resource.")
+	}
+
+	var retention *metav1.Duration
+	if recycleFlags.Retention != "" {
+		d, err := time.ParseDuration(recycleFlags.Retention)
+		if err != nil {
+			tlog.Panicf("✗ invalid retention %q: %v", recycleFlags.Retention, err)
+		}
+		retention = &metav1.Duration{Duration: d}
+	}
+
+	deletionPolicy := api.DeletionPolicy(recycleFlags.DeletionPolicy)
+	if deletionPolicy != api.DeletionPolicyKeep && deletionPolicy != api.DeletionPolicyPurge {
+		tlog.Panicf("✗ invalid deletion policy %q, must be one of: Keep|Purge.", recycleFlags.DeletionPolicy)
+	}
+
+	var webhookSettings *api.WebhookSettings
+	if recycleFlags.FailurePolicy != "" || recycleFlags.TimeoutSeconds != 0 {
+		webhookSettings = &api.WebhookSettings{}
+		if recycleFlags.FailurePolicy != "" {
+			webhookSettings.FailurePolicy = util.Ptr(admissionregistrationv1.FailurePolicyType(recycleFlags.FailurePolicy))
+		}
+		if recycleFlags.TimeoutSeconds != 0 {
+			webhookSettings.TimeoutSeconds = util.Ptr(recycleFlags.TimeoutSeconds)
+		}
+		if err := webhookSettings.Validate(); err != nil {
+			tlog.Panicf("✗ %v", err)
+		}
+	}
+
+	objectSelector, err := parseLabelSelector(recycleFlags.Selector)
+	if err != nil {
+		tlog.Panicf("✗ invalid selector %q: %v", recycleFlags.Selector, err)
+	}
+	namespaceSelector, err := parseLabelSelector(recycleFlags.NamespaceSelector)
+	if err != nil {
+		tlog.Panicf("✗ invalid namespace selector %q: %v", recycleFlags.NamespaceSelector, err)
 	}
 
+	newPolicy := func(target api.RecycleTarget) *api.RecyclePolicy {
+		policy := api.NewRecyclePolicyFor(target, recycleFlags.TargetNamespaces)
+		if recycleFlags.Name != "" {
+			policy.Name = recycleFlags.Name
+		}
+		policy.Retention = retention
+		policy.DeletionPolicy = deletionPolicy
+		policy.Webhook = webhookSettings
+		policy.Target.ObjectSelector = objectSelector
+		policy.Target.NamespaceSelector = namespaceSelector
+		return policy
+	}
+
+	var policies []*api.RecyclePolicy
+	var resources []api.TargetResource
 	for _, resource := range args {
 		gvr, err := kube.GetPreferredGroupVersionResourceFor(resource)
 		if err != nil {
@@ -73,12 +176,42 @@ func runRecycle(args []string) {
 			tlog.Errorf("✗ no resources found for %s, ignored.", resource)
 			continue
 		}
+		if single {
+			resources = append(resources, api.TargetResource{Group: gvr.Group, Resource: gvr.Resource})
+		} else {
+			policies = append(policies, newPolicy(api.RecycleTarget{Group: gvr.Group, Resource: gvr.Resource}))
+		}
+	}
+	if single {
+		for _, group := range recycleFlags.AllInGroup {
+			resources = append(resources, api.TargetResource{Group: util.If(group == "core", "", group), Resource: "*"})
+		}
+		target := api.RecycleTarget{Category: recycleFlags.Category}
+		if len(resources) > 0 {
+			target.Group, target.Resource = resources[0].Group, resources[0].Resource
+			target.Resources = resources[1:]
+		}
+		policies = append(policies, newPolicy(target))
+	}
 
-		recycleItem := api.NewRecyclePolicy(*gvr, recycleFlags.TargetNamespaces)
-		if err := krbclient.RecyclePolicy().Create(context.Background(), recycleItem, client.CreateOptions{}); err != nil {
+	for _, policy := range policies {
+		if err := api.BuiltinExclusions.Validate(&policy.Target); err != nil {
+			tlog.Errorf("✗ %v, ignored.", err)
+			continue
+		}
+		if err := krbclient.RecyclePolicy().Create(context.Background(), policy, client.CreateOptions{}); err != nil {
 			tlog.Panicf("✗ failed to create recycle policy: %v, ignored.", err)
 			continue
 		}
-		tlog.Printf("✓ create recycle policy [%s] done.", recycleItem.Name)
+		tlog.Printf("✓ create recycle policy [%s] done.", policy.Name)
+	}
+}
+
+// parseLabelSelector parses a label selector string, an empty string means no
+// selector at all.
+func parseLabelSelector(selector string) (*metav1.LabelSelector, error) {
+	if selector == "" {
+		return nil, nil
 	}
+	return metav1.ParseToLabelSelector(selector)
 }
@@ -0,0 +1,301 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// objectRef identifies a recycled object by group kind, namespace and name.
+type objectRef struct {
+	GroupKind schema.GroupKind
+	Namespace string
+	Name      string
+}
+
+// recycledNode is a RecycleItem in the dependency graph.
+type recycledNode struct {
+	item       *api.RecycleItem
+	obj        *unstructured.Unstructured
+	recycledAt time.Time
+}
+
+func (n *recycledNode) ref() objectRef {
+	return objectRef{
+		GroupKind: n.item.Object.ObjectGroupKind(),
+		Namespace: n.item.Object.Namespace,
+		Name:      n.item.Object.Name,
+	}
+}
+
+var (
+	namespaceGroupKind = schema.GroupKind{Kind: "Namespace"}
+	crdGroupKind       = schema.GroupKind{Group: "apiextensions.k8s.io", Kind: "CustomResourceDefinition"}
+	serviceGroupKind   = schema.GroupKind{Kind: "Service"}
+)
+
+// restoreTiers orders kinds for restore: namespaces and CRDs first, then
+// config, then services, then workloads. Kinds not listed come last.
+var restoreTiers = map[schema.GroupKind]int{
+	namespaceGroupKind: 0,
+	crdGroupKind:       1,
+
+	{Kind: "ResourceQuota"}:                                          2,
+	{Kind: "LimitRange"}:                                             2,
+	{Group: "networking.k8s.io", Kind: "NetworkPolicy"}:              2,
+	{Kind: "ServiceAccount"}:                                         2,
+	{Kind: "Secret"}:                                                 2,
+	{Kind: "ConfigMap"}:                                              2,
+	{Group: "storage.k8s.io", Kind: "StorageClass"}:                  2,
+	{Kind: "PersistentVolume"}:                                       2,
+	{Kind: "PersistentVolumeClaim"}:                                  2,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRole"}:        2,
+	{Group: "rbac.authorization.k8s.io", Kind: "ClusterRoleBinding"}: 2,
+	{Group: "rbac.authorization.k8s.io", Kind: "Role"}:               2,
+	{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}:        2,
+
+	serviceGroupKind: 3,
+
+	{Group: "apps", Kind: "DaemonSet"}:   4,
+	{Kind: "Pod"}:                        4,
+	{Kind: "ReplicationController"}:      4,
+	{Group: "apps", Kind: "ReplicaSet"}:  4,
+	{Group: "apps", Kind: "Deployment"}:  4,
+	{Group: "apps", Kind: "StatefulSet"}: 4,
+	{Group: "batch", Kind: "Job"}:        4,
+	{Group: "batch", Kind: "CronJob"}:    4,
+}
+
+func restoreTier(gk schema.GroupKind) int {
+	if tier, ok := restoreTiers[gk]; ok {
+		return tier
+	}
+	return 5
+}
+
+// podSpecPaths are the paths of the pod spec in workload kinds.
+var podSpecPaths = map[schema.GroupKind][]string{
+	{Kind: "Pod"}:                        {"spec"},
+	{Kind: "ReplicationController"}:      {"spec", "template", "spec"},
+	{Group: "apps", Kind: "DaemonSet"}:   {"spec", "template", "spec"},
+	{Group: "apps", Kind: "ReplicaSet"}:  {"spec", "template", "spec"},
+	{Group: "apps", Kind: "Deployment"}:  {"spec", "template", "spec"},
+	{Group: "apps", Kind: "StatefulSet"}: {"spec", "template", "spec"},
+	{Group: "batch", Kind: "Job"}:        {"spec", "template", "spec"},
+	{Group: "batch", Kind: "CronJob"}:    {"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+// resolveDependencies returns the selected RecycleItems together with the
+// candidate RecycleItems they depend on, recycled within window of any
+// selected RecycleItem, sorted in a safe order to restore.
+func resolveDependencies(selected, candidates []api.RecycleItem, window time.Duration) []api.RecycleItem {
+	selectedNodes := buildRecycledNodes(selected)
+	candidateNodes := buildRecycledNodes(candidates)
+
+	// Only candidates from the same deletion wave are considered.
+	candidateNodes = slices.DeleteFunc(candidateNodes, func(candidate *recycledNode) bool {
+		return !slices.ContainsFunc(selectedNodes, func(node *recycledNode) bool {
+			d := candidate.recycledAt.Sub(node.recycledAt)
+			return d <= window && d >= -window
+		})
+	})
+
+	// When an object was recycled several times, its latest copy wins.
+	byRef := map[objectRef]*recycledNode{}
+	for _, node := range candidateNodes {
+		if existing, ok := byRef[node.ref()]; !ok || node.recycledAt.After(existing.recycledAt) {
+			byRef[node.ref()] = node
+		}
+	}
+
+	visited := map[string]bool{}
+	var result []*recycledNode
+	queue := selectedNodes
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+		if visited[node.item.Name] {
+			continue
+		}
+		visited[node.item.Name] = true
+		result = append(result, node)
+
+		for _, ref := range dependencyRefs(node, candidateNodes) {
+			if dependency, ok := byRef[ref]; ok && !visited[dependency.item.Name] {
+				tlog.Printf("» [%s: %s] depends on [%s: %s] from RecycleItem [%s].", node.item.Object.GroupResource().String(), node.item.Object.Key(), dependency.item.Object.GroupResource().String(), dependency.item.Object.Key(), dependency.item.Name)
+				queue = append(queue, dependency)
+			}
+		}
+	}
+
+	slices.SortStableFunc(result, func(a, b *recycledNode) int {
+		return cmp.Or(
+			cmp.Compare(restoreTier(a.item.Object.ObjectGroupKind()), restoreTier(b.item.Object.ObjectGroupKind())),
+			a.recycledAt.Compare(b.recycledAt),
+			cmp.Compare(a.item.Name, b.item.Name),
+		)
+	})
+
+	items := make([]api.RecycleItem, 0, len(result))
+	for _, node := range result {
+		items = append(items, *node.item)
+	}
+	return items
+}
+
+func buildRecycledNodes(items []api.RecycleItem) []*recycledNode {
+	var result []*recycledNode
+	for i := range items {
+		obj, err := items[i].Object.Unstructured()
+		if err != nil {
+			tlog.Printf("✗ failed to get unstructured object from RecycleItem [%s]: %v, ignored.", items[i].Name, err)
+			continue
+		}
+		recycledAt, err := items[i].RecycledAt()
+		if err != nil {
+			recycledAt = items[i].CreationTimestamp.Time
+		}
+		result = append(result, &recycledNode{item: &items[i], obj: obj, recycledAt: recycledAt})
+	}
+	return result
+}
+
+// dependencyRefs returns the objects the recycled object depends on: its
+// namespace, its CRD, its owners, the objects its pod spec references and the
+// services selecting its pods.
+func dependencyRefs(node *recycledNode, candidates []*recycledNode) []objectRef {
+	var result []objectRef
+	namespace := node.item.Object.Namespace
+	gk := node.item.Object.ObjectGroupKind()
+
+	if namespace != "" {
+		result = append(result, objectRef{GroupKind: namespaceGroupKind, Name: namespace})
+	}
+	if node.item.Object.Group != "" {
+		result = append(result, objectRef{GroupKind: crdGroupKind, Name: node.item.Object.Resource + "." + node.item.Object.Group})
+	}
+
+	for _, owner := range node.obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil {
+			continue
+		}
+		// Owners of namespaced objects may be cluster-scoped.
+		ownerGroupKind := gv.WithKind(owner.Kind).GroupKind()
+		result = append(result,
+			objectRef{GroupKind: ownerGroupKind, Namespace: namespace, Name: owner.Name},
+			objectRef{GroupKind: ownerGroupKind, Name: owner.Name},
+		)
+	}
+
+	podSpecPath, ok := podSpecPaths[gk]
+	if !ok {
+		return result
+	}
+	podSpec, ok, _ := unstructured.NestedMap(node.obj.Object, podSpecPath...)
+	if !ok {
+		return result
+	}
+	for _, ref := range podSpecRefs(podSpec) {
+		ref.Namespace = namespace
+		result = append(result, ref)
+	}
+
+	// Pod labels live in the metadata next to the pod spec.
+	podMetadataPath := append(slices.Clone(podSpecPath[:len(podSpecPath)-1]), "metadata", "labels")
+	podLabels, _, _ := unstructured.NestedStringMap(node.obj.Object, podMetadataPath...)
+	if len(podLabels) == 0 {
+		return result
+	}
+	for _, candidate := range candidates {
+		if candidate.item.Object.ObjectGroupKind() != serviceGroupKind || candidate.item.Object.Namespace != namespace {
+			continue
+		}
+		selector, ok, _ := unstructured.NestedStringMap(candidate.obj.Object, "spec", "selector")
+		if ok && len(selector) > 0 && labels.SelectorFromSet(selector).Matches(labels.Set(podLabels)) {
+			result = append(result, candidate.ref())
+		}
+	}
+	return result
+}
+
+// podSpecRefs returns the config maps, secrets, persistent volume claims and
+// service account referenced by the pod spec, without namespace.
+func podSpecRefs(podSpec map[string]any) []objectRef {
+	var result []objectRef
+	add := func(kind, name string) {
+		if name != "" {
+			result = append(result, objectRef{GroupKind: schema.GroupKind{Kind: kind}, Name: name})
+		}
+	}
+
+	for _, name := range []string{"serviceAccountName", "serviceAccount"} {
+		sa, _, _ := unstructured.NestedString(podSpec, name)
+		add("ServiceAccount", sa)
+	}
+	for _, secret := range nestedMaps(podSpec, "imagePullSecrets") {
+		add("Secret", nestedString(secret, "name"))
+	}
+
+	for _, volume := range nestedMaps(podSpec, "volumes") {
+		add("ConfigMap", nestedString(volume, "configMap", "name"))
+		add("Secret", nestedString(volume, "secret", "secretName"))
+		add("PersistentVolumeClaim", nestedString(volume, "persistentVolumeClaim", "claimName"))
+		for _, source := range nestedMaps(volume, "projected", "sources") {
+			add("ConfigMap", nestedString(source, "configMap", "name"))
+			add("Secret", nestedString(source, "secret", "name"))
+		}
+	}
+
+	for _, field := range []string{"initContainers", "containers", "ephemeralContainers"} {
+		for _, container := range nestedMaps(podSpec, field) {
+			for _, envFrom := range nestedMaps(container, "envFrom") {
+				add("ConfigMap", nestedString(envFrom, "configMapRef", "name"))
+				add("Secret", nestedString(envFrom, "secretRef", "name"))
+			}
+			for _, env := range nestedMaps(container, "env") {
+				add("ConfigMap", nestedString(env, "valueFrom", "configMapKeyRef", "name"))
+				add("Secret", nestedString(env, "valueFrom", "secretKeyRef", "name"))
+			}
+		}
+	}
+	return result
+}
+
+func nestedString(obj map[string]any, fields ...string) string {
+	s, _, _ := unstructured.NestedString(obj, fields...)
+	return s
+}
+
+func nestedMaps(obj map[string]any, fields ...string) []map[string]any {
+	list, _, _ := unstructured.NestedSlice(obj, fields...)
+	var result []map[string]any
+	for _, v := range list {
+		if m, ok := v.(map[string]any); ok {
+			result = append(result, m)
+		}
+	}
+	return result
+}
@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestRecycleItem(name, group, kind, resource, namespace string, recycledAt int64, raw string) api.RecycleItem {
+	return api.RecycleItem{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{api.RecycledAtLabel: fmt.Sprintf("%d", recycledAt)},
+		},
+		Object: api.RecycledObject{
+			Group:     group,
+			Version:   "v1",
+			Kind:      kind,
+			Resource:  resource,
+			Namespace: namespace,
+			Name:      name,
+			Raw:       []byte(raw),
+		},
+	}
+}
+
+func TestResolveDependencies(t *testing.T) {
+	deploy := newTestRecycleItem("web", "apps", "Deployment", "deployments", "dev", 1000, `{
+		"apiVersion": "apps/v1", "kind": "Deployment", "metadata": {"name": "web", "namespace": "dev"},
+		"spec": {"template": {
+			"metadata": {"labels": {"app": "web"}},
+			"spec": {
+				"serviceAccountName": "web-sa",
+				"volumes": [{"name": "config", "configMap": {"name": "web-config"}}],
+				"containers": [{"name": "web", "envFrom": [{"secretRef": {"name": "web-secret"}}]}]
+			}
+		}}
+	}`)
+	candidates := []api.RecycleItem{
+		deploy,
+		newTestRecycleItem("web-svc", "", "Service", "services", "dev", 1010, `{
+			"apiVersion": "v1", "kind": "Service", "metadata": {"name": "web-svc", "namespace": "dev"},
+			"spec": {"selector": {"app": "web"}}
+		}`),
+		newTestRecycleItem("web-config", "", "ConfigMap", "configmaps", "dev", 990, `{
+			"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web-config", "namespace": "dev"}
+		}`),
+		newTestRecycleItem("web-secret", "", "Secret", "secrets", "dev", 1020, `{
+			"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "web-secret", "namespace": "dev"}
+		}`),
+		newTestRecycleItem("web-sa", "", "ServiceAccount", "serviceaccounts", "dev", 1030, `{
+			"apiVersion": "v1", "kind": "ServiceAccount", "metadata": {"name": "web-sa", "namespace": "dev"}
+		}`),
+		newTestRecycleItem("dev", "", "Namespace", "namespaces", "", 1040, `{
+			"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "dev"}
+		}`),
+		// recycled long before the deployment
+		newTestRecycleItem("web-secret-old", "", "Secret", "secrets", "dev", 10, `{
+			"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "web-secret", "namespace": "dev"}
+		}`),
+		// not referenced by the deployment
+		newTestRecycleItem("other-config", "", "ConfigMap", "configmaps", "dev", 1000, `{
+			"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "other-config", "namespace": "dev"}
+		}`),
+		// in another namespace
+		newTestRecycleItem("web-config-prod", "", "ConfigMap", "configmaps", "prod", 1000, `{
+			"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "web-config", "namespace": "prod"}
+		}`),
+	}
+
+	result := resolveDependencies([]api.RecycleItem{deploy}, candidates, time.Minute)
+
+	var got []string
+	for _, item := range result {
+		got = append(got, item.Name)
+	}
+	desired := []string{"dev", "web-config", "web-secret", "web-sa", "web-svc", "web"}
+	if !slices.Equal(got, desired) {
+		t.Errorf("✗ expected %v, got %v", desired, got)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// parseRedactedValues parses values of redacted fields in the form of
+// path=value.
+func parseRedactedValues(values []string) (map[string]string, error) {
+	result := make(map[string]string, len(values))
+	for _, value := range values {
+		path, v, ok := strings.Cut(value, "=")
+		if !ok || !strings.HasPrefix(path, "$") {
+			return nil, fmt.Errorf("invalid redacted value %q, expected path=value such as $.data.password=s3cr3t", value)
+		}
+		result[path] = v
+	}
+	return result, nil
+}
+
+// fillRedactedFields sets the fields redacted from the recycled object to
+// the given values, or to the values read from prompt if it is not nil.
+// Fields without a value stay unset and are returned.
+func fillRedactedFields(obj *unstructured.Unstructured, redacted []api.RedactedField, values map[string]string, prompt *bufio.Reader) []string {
+	var missing []string
+	var skipped []api.RedactedField
+	for _, field := range redacted {
+		value, ok := values[field.Path]
+		if !ok && prompt != nil {
+			fmt.Printf("Value of redacted %s %s (leave empty to skip): ", field.Type, field.Path)
+			answer, _ := prompt.ReadString('\n')
+			value = strings.TrimRight(answer, "\r\n")
+			ok = strings.TrimSpace(value) != ""
+		}
+		if !ok {
+			missing = append(missing, field.Path)
+			skipped = append(skipped, field)
+			continue
+		}
+		if err := api.SetRedactedField(obj.Object, field.After(skipped), value); err != nil {
+			tlog.Printf("✗ failed to set redacted field %s: %v", field.Path, err)
+			missing = append(missing, field.Path)
+			skipped = append(skipped, field)
+		}
+	}
+	return missing
+}
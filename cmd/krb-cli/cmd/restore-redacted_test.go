@@ -0,0 +1,100 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"bufio"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestFillRedactedFields(t *testing.T) {
+	redacted := []api.RedactedField{
+		{Path: "$.spec.containers[0].env[0]", Type: "object"},
+		{Path: "$.spec.containers[0].env[1].value", Type: "string"},
+		{Path: "$.spec.replicas", Type: "number"},
+	}
+	obj := `{"spec": {"containers": [{"name": "app", "env": [{"name": "TOKEN"}]}]}}`
+
+	testdata := []struct {
+		name    string
+		values  []string
+		prompt  string
+		missing []string
+		desired string
+	}{
+		{
+			name:    "values",
+			values:  []string{`$.spec.containers[0].env[0]={"name": "LOG_LEVEL", "value": "debug"}`, "$.spec.containers[0].env[1].value=s3cr3t=="},
+			missing: []string{"$.spec.replicas"},
+			desired: `{"spec": {"containers": [{"name": "app", "env": [{"name": "LOG_LEVEL", "value": "debug"}, {"name": "TOKEN", "value": "s3cr3t=="}]}]}}`,
+		},
+		{
+			name:    "prompt",
+			values:  []string{"$.spec.replicas=3"},
+			prompt:  "\n hunter2 \n",
+			missing: []string{"$.spec.containers[0].env[0]"},
+			desired: `{"spec": {"replicas": 3, "containers": [{"name": "app", "env": [{"name": "TOKEN", "value": " hunter2 "}]}]}}`,
+		},
+		{
+			name:    "invalid value",
+			values:  []string{"$.spec.replicas=three"},
+			missing: []string{"$.spec.containers[0].env[0]", "$.spec.containers[0].env[1].value", "$.spec.replicas"},
+			desired: obj,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := parseRedactedValues(tt.values)
+			if err != nil {
+				t.Fatalf("✗ failed to parse values: %v", err)
+			}
+			var prompt *bufio.Reader
+			if tt.prompt != "" {
+				prompt = bufio.NewReader(strings.NewReader(tt.prompt))
+			}
+			u := &unstructured.Unstructured{}
+			if err := json.Unmarshal([]byte(obj), &u.Object); err != nil {
+				t.Fatalf("✗ failed to unmarshal object: %v", err)
+			}
+
+			missing := fillRedactedFields(u, redacted, values, prompt)
+			if !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("✗ expected missing %v, got %v", tt.missing, missing)
+			}
+			var desired map[string]any
+			if err := json.Unmarshal([]byte(tt.desired), &desired); err != nil {
+				t.Fatalf("✗ failed to unmarshal desired object: %v", err)
+			}
+			got, _ := json.Marshal(u.Object)
+			var actual map[string]any
+			_ = json.Unmarshal(got, &actual)
+			if !reflect.DeepEqual(actual, desired) {
+				t.Errorf("✗ expected %s, got %s", tt.desired, got)
+			}
+		})
+	}
+
+	if _, err := parseRedactedValues([]string{"spec.replicas=3"}); err == nil {
+		t.Errorf("✗ expected error for value without JSONPath")
+	}
+}
@@ -0,0 +1,102 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"slices"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+var (
+	roleBindingGroupKind = schema.GroupKind{Group: "rbac.authorization.k8s.io", Kind: "RoleBinding"}
+)
+
+// relocateObject moves the namespaced object to toNamespace and renames the
+// object to newName, leaving either unchanged when empty. References to the
+// old namespace and name are fixed up where that is safe:
+//   - owner references are dropped when the namespace changes, owners can't
+//     live in another namespace;
+//   - service account subjects of role bindings in the old namespace follow
+//     the object to the new namespace;
+//   - labels, workload selectors and service selectors whose value is the old
+//     name are renamed, so a renamed clone doesn't select the original pods.
+func relocateObject(obj *unstructured.Unstructured, toNamespace, newName string) {
+	oldNamespace, oldName := obj.GetNamespace(), obj.GetName()
+	gk := obj.GroupVersionKind().GroupKind()
+
+	if toNamespace != "" && oldNamespace != "" && toNamespace != oldNamespace {
+		obj.SetNamespace(toNamespace)
+		obj.SetOwnerReferences(nil)
+
+		if gk == roleBindingGroupKind {
+			subjects := nestedMaps(obj.Object, "subjects")
+			for _, subject := range subjects {
+				if subject["kind"] == "ServiceAccount" && subject["namespace"] == oldNamespace {
+					subject["namespace"] = toNamespace
+				}
+			}
+			setNestedMaps(obj.Object, subjects, "subjects")
+		}
+	}
+
+	if newName != "" && newName != oldName {
+		obj.SetName(newName)
+
+		renameLabelValues(obj.Object, oldName, newName, "metadata", "labels")
+		if gk == serviceGroupKind {
+			renameLabelValues(obj.Object, oldName, newName, "spec", "selector")
+		}
+		if podSpecPath, ok := podSpecPaths[gk]; ok && len(podSpecPath) > 1 {
+			// The selector lives next to the pod template.
+			templatePath := podSpecPath[:len(podSpecPath)-1]
+			selectorPath := append(slices.Clone(templatePath[:len(templatePath)-1]), "selector")
+			renameLabelValues(obj.Object, oldName, newName, append(slices.Clone(selectorPath), "matchLabels")...)
+			renameLabelValues(obj.Object, oldName, newName, append(slices.Clone(templatePath), "metadata", "labels")...)
+			if gk == (schema.GroupKind{Kind: "ReplicationController"}) {
+				renameLabelValues(obj.Object, oldName, newName, selectorPath...)
+			}
+		}
+	}
+}
+
+// renameLabelValues replaces the label values equal to oldValue at the path
+// with newValue.
+func renameLabelValues(obj map[string]any, oldValue, newValue string, fields ...string) {
+	labels, ok, _ := unstructured.NestedStringMap(obj, fields...)
+	if !ok {
+		return
+	}
+	for k, v := range labels {
+		if v == oldValue {
+			labels[k] = newValue
+		}
+	}
+	_ = unstructured.SetNestedStringMap(obj, labels, fields...)
+}
+
+func setNestedMaps(obj map[string]any, maps []map[string]any, fields ...string) {
+	if len(maps) == 0 {
+		return
+	}
+	list := make([]any, 0, len(maps))
+	for _, m := range maps {
+		list = append(list, m)
+	}
+	_ = unstructured.SetNestedSlice(obj, list, fields...)
+}
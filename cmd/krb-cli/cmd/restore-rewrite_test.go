@@ -0,0 +1,101 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestRelocateObject(t *testing.T) {
+	testdata := []struct {
+		name        string
+		toNamespace string
+		newName     string
+		obj         string
+		desired     string
+	}{
+		{
+			name:        "deployment-to-namespace-with-new-name",
+			toNamespace: "scratch",
+			newName:     "web-copy",
+			obj: `{"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": {"name": "web", "namespace": "prod", "labels": {"app": "web", "tier": "web"},
+					"ownerReferences": [{"apiVersion": "v1", "kind": "ConfigMap", "name": "owner", "uid": "1"}]},
+				"spec": {"selector": {"matchLabels": {"app": "web"}},
+					"template": {"metadata": {"labels": {"app": "web", "version": "v1"}}, "spec": {}}}}`,
+			desired: `{"apiVersion": "apps/v1", "kind": "Deployment",
+				"metadata": {"name": "web-copy", "namespace": "scratch", "labels": {"app": "web-copy", "tier": "web-copy"}},
+				"spec": {"selector": {"matchLabels": {"app": "web-copy"}},
+					"template": {"metadata": {"labels": {"app": "web-copy", "version": "v1"}}, "spec": {}}}}`,
+		},
+		{
+			name:    "service-with-new-name",
+			newName: "web-copy",
+			obj: `{"apiVersion": "v1", "kind": "Service",
+				"metadata": {"name": "web", "namespace": "prod"},
+				"spec": {"selector": {"app": "web", "tier": "frontend"}}}`,
+			desired: `{"apiVersion": "v1", "kind": "Service",
+				"metadata": {"name": "web-copy", "namespace": "prod"},
+				"spec": {"selector": {"app": "web-copy", "tier": "frontend"}}}`,
+		},
+		{
+			name:        "rolebinding-to-namespace",
+			toNamespace: "scratch",
+			obj: `{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "RoleBinding",
+				"metadata": {"name": "web", "namespace": "prod"},
+				"subjects": [
+					{"kind": "ServiceAccount", "name": "web", "namespace": "prod"},
+					{"kind": "ServiceAccount", "name": "monitor", "namespace": "monitoring"},
+					{"kind": "User", "name": "alice"}
+				]}`,
+			desired: `{"apiVersion": "rbac.authorization.k8s.io/v1", "kind": "RoleBinding",
+				"metadata": {"name": "web", "namespace": "scratch"},
+				"subjects": [
+					{"kind": "ServiceAccount", "name": "web", "namespace": "scratch"},
+					{"kind": "ServiceAccount", "name": "monitor", "namespace": "monitoring"},
+					{"kind": "User", "name": "alice"}
+				]}`,
+		},
+		{
+			name:        "cluster-scoped-to-namespace",
+			toNamespace: "scratch",
+			obj:         `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "prod"}}`,
+			desired:     `{"apiVersion": "v1", "kind": "Namespace", "metadata": {"name": "prod"}}`,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, desired := &unstructured.Unstructured{}, &unstructured.Unstructured{}
+			if err := json.Unmarshal([]byte(tt.obj), obj); err != nil {
+				t.Fatalf("✗ failed to unmarshal object: %v", err)
+			}
+			if err := json.Unmarshal([]byte(tt.desired), desired); err != nil {
+				t.Fatalf("✗ failed to unmarshal desired object: %v", err)
+			}
+
+			relocateObject(obj, tt.toNamespace, tt.newName)
+			if !reflect.DeepEqual(obj.Object, desired.Object) {
+				t.Errorf("✗ expected %v, got %v", desired.Object, obj.Object)
+			}
+		})
+	}
+}
@@ -17,20 +17,43 @@ limitations under the License.
 package cmd
 
 import (
+	"bufio"
 	"context"
+	"os"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/ketches/kube-recycle-bin/internal/api"
 	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
 	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
 	"github.com/spf13/cobra"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 type RestoreFlags struct {
-	ObjectResource  string
-	ObjectNamespace string
+	ObjectResource   string
+	ObjectNamespace  string
+	WithDependencies bool
+	DependencyWindow time.Duration
+	ToNamespace      string
+	NewName          string
+	OnConflict       string
+	SanitizeRules    []string
+	RedactedValues   []string
+	PromptRedacted   bool
+	DryRun           string
 }
 
 var restoreFlags RestoreFlags
@@ -49,6 +72,32 @@ krb-cli restore --object-resource deployments foo
 
 # Restore RecycleItem deployments foo-deploy, service foo-svc and filter by object namespace dev
 krb-cli restore --object-namespace dev foo-deploy foo-svc
+
+# Restore RecycleItem foo-deploy together with the recycled config maps, secrets, service account,
+# services and namespace it depends on, which were recycled within 5 minutes of it
+krb-cli restore foo-deploy --with-dependencies --dependency-window 5m
+
+# Clone the recycled resource object from RecycleItem foo-deploy into namespace scratch under name foo-copy,
+# the RecycleItem is kept
+krb-cli restore foo-deploy --to-namespace scratch --new-name foo-copy
+
+# Restore RecycleItem foo-deploy and overwrite the object if it already exists
+krb-cli restore foo-deploy --on-conflict overwrite
+
+# Restore RecycleItem foo-deploy without its replicas and paused fields
+krb-cli restore foo-deploy --sanitize Deployment.apps:spec.replicas --sanitize Deployment.apps:spec.paused
+
+# Restore RecycleItem foo-secret with the value of a field its RecyclePolicy redacted
+krb-cli restore foo-secret --set-redacted '$.data.password=czNjcjN0'
+
+# Restore RecycleItem foo-deploy and prompt for the values of the redacted fields
+krb-cli restore foo-deploy --prompt-redacted
+
+# Print the object RecycleItem foo-deploy would restore without sending it
+krb-cli restore foo-deploy --dry-run=client
+
+# Restore RecycleItem foo-deploy with server-side dry-run, nothing is persisted and the RecycleItem is kept
+krb-cli restore foo-deploy --dry-run=server
 `,
 
 	Run: func(cmd *cobra.Command, args []string) {
@@ -63,38 +112,320 @@ func init() {
 	restoreCmd.Flags().StringVarP(&restoreFlags.ObjectResource, "object-resource", "", "", "Restore recycled resource objects filtered by the specified object resource")
 	restoreCmd.Flags().StringVarP(&restoreFlags.ObjectNamespace, "object-namespace", "", "", "Restore recycled resource objects filtered by the specified object namespace")
 
+	restoreCmd.Flags().BoolVarP(&restoreFlags.WithDependencies, "with-dependencies", "", false, "Restore recycled resource objects together with the recycled objects they depend on, such as owners, config maps, secrets, service accounts and services")
+	restoreCmd.Flags().DurationVarP(&restoreFlags.DependencyWindow, "dependency-window", "", time.Minute*10, "Only restore dependencies recycled within the specified duration of the specified recycle items")
+
+	restoreCmd.Flags().StringVarP(&restoreFlags.ToNamespace, "to-namespace", "", "", "Restore namespaced recycled resource objects into the specified namespace instead of the original one, the RecycleItems are kept")
+	restoreCmd.Flags().StringVarP(&restoreFlags.NewName, "new-name", "", "", "Restore the recycled resource object under the specified name instead of the original one, the RecycleItem is kept")
+
+	restoreCmd.Flags().StringVarP(&restoreFlags.OnConflict, "on-conflict", "", conflictFail, "What to do when the recycled resource object already exists. One of: fail|skip|overwrite|rename")
+
+	restoreCmd.Flags().StringArrayVarP(&restoreFlags.SanitizeRules, "sanitize", "", nil, "Remove the field from recycled resource objects of the kind before restoring them, in the form of Kind.group:field such as Deployment.apps:spec.paused")
+	restoreCmd.Flags().StringArrayVarP(&restoreFlags.RedactedValues, "set-redacted", "", nil, "Set the field redacted from recycled resource objects to the value before restoring them, in the form of path=value such as $.spec.template.spec.containers[0].env[1].value=s3cr3t")
+	restoreCmd.Flags().BoolVarP(&restoreFlags.PromptRedacted, "prompt-redacted", "", false, "Prompt for the values of the fields redacted from recycled resource objects that are not set with --set-redacted")
+	restoreCmd.Flags().StringVarP(&restoreFlags.DryRun, "dry-run", "", dryRunNone, "Only print or validate the recycled resource objects to restore. One of: none|client|server")
+
 	restoreCmd.RegisterFlagCompletionFunc("object-resource", completion.RecycleItemGroupResource)
 	restoreCmd.RegisterFlagCompletionFunc("object-namespace", completion.RecycleItemNamespace)
+	restoreCmd.RegisterFlagCompletionFunc("on-conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{conflictFail, conflictSkip, conflictOverwrite, conflictRename}, cobra.ShellCompDirectiveNoFileComp
+	})
+	restoreCmd.RegisterFlagCompletionFunc("dry-run", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{dryRunNone, dryRunClient, dryRunServer}, cobra.ShellCompDirectiveNoFileComp
+	})
 }
 
 func runRestore(args []string) {
 	if len(args) == 0 {
 		tlog.Panicf("✗ please specify recycle items to restore.")
 	}
+	if !slices.Contains([]string{conflictFail, conflictSkip, conflictOverwrite, conflictRename}, restoreFlags.OnConflict) {
+		tlog.Panicf("✗ invalid --on-conflict %q, must be one of: fail|skip|overwrite|rename.", restoreFlags.OnConflict)
+	}
+	if !slices.Contains([]string{dryRunNone, dryRunClient, dryRunServer}, restoreFlags.DryRun) {
+		tlog.Panicf("✗ invalid --dry-run %q, must be one of: none|client|server.", restoreFlags.DryRun)
+	}
+	if restoreFlags.NewName != "" && (len(args) > 1 || restoreFlags.WithDependencies) {
+		tlog.Panicf("✗ --new-name can only be used to restore a single recycle item.")
+	}
+
+	registerSanitizeRules(restoreFlags.SanitizeRules)
+	redactedValues, err := parseRedactedValues(restoreFlags.RedactedValues)
+	if err != nil {
+		tlog.Panicf("✗ %v", err)
+	}
+	var prompt *bufio.Reader
+	if restoreFlags.PromptRedacted {
+		prompt = bufio.NewReader(os.Stdin)
+	}
 
+	var recycleItems []api.RecycleItem
 	for _, recycleItemName := range args {
 		recycleItem, err := krbclient.RecycleItem().Get(context.Background(), recycleItemName, client.GetOptions{})
 		if err != nil {
 			tlog.Printf("✗ failed to get RecycleItem [%s]: %v, ignored.", recycleItemName, err)
 			continue
 		}
+		if err := krbclient.LoadPayload(context.Background(), recycleItem); err != nil {
+			tlog.Printf("✗ failed to load payload of RecycleItem [%s]: %v, ignored.", recycleItemName, err)
+			continue
+		}
+		recycleItems = append(recycleItems, *recycleItem)
+	}
 
-		unstructuredObj, err := recycleItem.Object.Unstructured()
+	if restoreFlags.WithDependencies && len(recycleItems) > 0 {
+		candidates, err := krbclient.RecycleItem().List(context.Background(), client.ListOptions{})
 		if err != nil {
-			tlog.Printf("✗ failed to get unstructured object from RecycleItem [%s]: %v, ignored.", recycleItemName, err)
-			continue
+			tlog.Panicf("✗ failed to list RecycleItem: %v", err)
+		}
+		for i := range candidates.Items {
+			if err := krbclient.LoadPayload(context.Background(), &candidates.Items[i]); err != nil {
+				tlog.Printf("✗ failed to load payload of RecycleItem [%s]: %v, ignored.", candidates.Items[i].Name, err)
+			}
 		}
+		recycleItems = resolveDependencies(recycleItems, candidates.Items, restoreFlags.DependencyWindow)
+		tlog.Printf("» restoring %d recycle items with dependencies in order:", len(recycleItems))
+		for _, recycleItem := range recycleItems {
+			tlog.Printf("  - %s [%s: %s]", recycleItem.Name, recycleItem.Object.GroupResource().String(), recycleItem.Object.Key())
+		}
+	}
+
+	var results []restoreResult
+	for i := range recycleItems {
+		results = append(results, restoreRecycleItem(&recycleItems[i], redactedValues, prompt))
+	}
+	printRestoreSummary(results)
+}
+
+const (
+	conflictFail      = "fail"
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictRename    = "rename"
+
+	dryRunNone   = "none"
+	dryRunClient = "client"
+	dryRunServer = "server"
+
+	// restoreFieldManager is the field manager of server-side applies that
+	// overwrite existing objects on restore.
+	restoreFieldManager = "krb-cli-restore"
+)
+
+// restoreResult is the outcome of restoring a single RecycleItem.
+type restoreResult struct {
+	RecycleItem string
+	Object      string
+	Strategy    string
+	Outcome     string
+}
+
+// restoreRecycleItem restores the recycled resource object from the RecycleItem,
+// and deletes the RecycleItem after the object is restored in place. Fields
+// redacted from the object are set to redactedValues or prompted for.
+func restoreRecycleItem(recycleItem *api.RecycleItem, redactedValues map[string]string, prompt *bufio.Reader) restoreResult {
+	result := restoreResult{
+		RecycleItem: recycleItem.Name,
+		Object:      recycleItem.Object.GroupResource().String() + ": " + recycleItem.Object.Key(),
+	}
+
+	if recycleItem.Object.Truncated {
+		tlog.Printf("✗ RecycleItem [%s] only kept the metadata of the recycled object, which was too large, ignored.", recycleItem.Name)
+		result.Outcome = "failed: truncated"
+		return result
+	}
+	unstructuredObj, err := recycleItem.Object.Unstructured()
+	if err != nil {
+		tlog.Printf("✗ failed to get unstructured object from RecycleItem [%s]: %v, ignored.", recycleItem.Name, err)
+		result.Outcome = "failed: " + err.Error()
+		return result
+	}
+
+	if len(recycleItem.Object.Redacted) > 0 {
+		tlog.Printf("» RecycleItem [%s] has %d fields redacted by its RecyclePolicy.", recycleItem.Name, len(recycleItem.Object.Redacted))
+		if missing := fillRedactedFields(unstructuredObj, recycleItem.Object.Redacted, redactedValues, prompt); len(missing) > 0 {
+			tlog.Printf("» recycled resource object [%s] is incomplete, restoring it without the redacted fields: %s", recycleItem.Object.Key(), strings.Join(missing, ", "))
+		}
+	}
+
+	relocateObject(unstructuredObj, restoreFlags.ToNamespace, restoreFlags.NewName)
+	fixOwnerReferences(unstructuredObj)
+
+	if restoreFlags.DryRun == dryRunClient {
+		objContent, err := objectYAML(unstructuredObj)
+		if err != nil {
+			tlog.Printf("✗ failed to render recycled resource object [%s] in YAML format: %v", recycleItem.Object.Key(), err)
+			result.Outcome = "failed: " + err.Error()
+			return result
+		}
+		tlog.Printf("# [%s: %s] from RecycleItem [%s] (dry run)", recycleItem.Object.GroupResource().String(), api.NamespacedKey(unstructuredObj.GetNamespace(), unstructuredObj.GetName()), recycleItem.Name)
+		tlog.Print(objContent)
+		result.Outcome = "restored (dry run)"
+		return result
+	}
 
-		if _, err := kube.DynamicClient().Resource(recycleItem.Object.GroupVersionResource()).Namespace(recycleItem.Object.Namespace).Create(context.Background(), unstructuredObj, metav1.CreateOptions{}); err != nil {
-			tlog.Printf("✗ failed to restore recycled resource object [%s]: %v", recycleItem.Object.Key(), err)
-		} else {
-			tlog.Printf("✓ restored recycled resource object [%s: %s] done.", recycleItem.Object.GroupResource().String(), recycleItem.Object.Key())
-			// delete the recycle item after successful restore
-			if err := krbclient.RecycleItem().Delete(context.Background(), recycleItemName, client.DeleteOptions{}); err != nil {
-				tlog.Printf("✗ failed to automatically delete RecycleItem [%s] after restore: %v", recycleItemName, err)
-			} else {
-				tlog.Printf("✓ automatically deleted RecycleItem [%s] after restore.", recycleItemName)
+	var dryRun []string
+	if restoreFlags.DryRun == dryRunServer {
+		dryRun = []string{metav1.DryRunAll}
+	}
+	resourceClient := kube.DynamicClient().Resource(recycleItem.Object.GroupVersionResource()).Namespace(unstructuredObj.GetNamespace())
+
+	_, err = resourceClient.Create(context.Background(), unstructuredObj, metav1.CreateOptions{DryRun: dryRun})
+	if k8serrors.IsAlreadyExists(err) {
+		result.Strategy = restoreFlags.OnConflict
+		tlog.Printf("» recycled resource object [%s] already exists, applying conflict strategy %s.", api.NamespacedKey(unstructuredObj.GetNamespace(), unstructuredObj.GetName()), restoreFlags.OnConflict)
+
+		switch restoreFlags.OnConflict {
+		case conflictSkip:
+			tlog.Printf("» skipped restoring recycled resource object [%s].", recycleItem.Object.Key())
+			result.Outcome = "skipped"
+			return result
+		case conflictOverwrite:
+			_, err = resourceClient.Apply(context.Background(), unstructuredObj.GetName(), unstructuredObj, metav1.ApplyOptions{
+				FieldManager: restoreFieldManager,
+				Force:        true,
+				DryRun:       dryRun,
+			})
+		case conflictRename:
+			baseName := unstructuredObj.GetName()
+			for range 5 {
+				relocateObject(unstructuredObj, "", baseName+"-restored-"+rand.String(5))
+				if _, err = resourceClient.Create(context.Background(), unstructuredObj, metav1.CreateOptions{DryRun: dryRun}); !k8serrors.IsAlreadyExists(err) {
+					break
+				}
 			}
 		}
 	}
+
+	relocated := unstructuredObj.GetNamespace() != recycleItem.Object.Namespace || unstructuredObj.GetName() != recycleItem.Object.Name
+	objKey := recycleItem.Object.Key()
+	if relocated {
+		objKey += " -> " + api.NamespacedKey(unstructuredObj.GetNamespace(), unstructuredObj.GetName())
+	}
+
+	if err != nil {
+		tlog.Printf("✗ failed to restore recycled resource object [%s]: %v", objKey, err)
+		result.Outcome = "failed: " + err.Error()
+		return result
+	}
+
+	dryRunSuffix := util.If(len(dryRun) > 0, " (server dry run)", "")
+	tlog.Printf("✓ restored recycled resource object [%s: %s] done%s.", recycleItem.Object.GroupResource().String(), objKey, dryRunSuffix)
+	switch {
+	case result.Strategy == conflictOverwrite:
+		result.Outcome = "overwritten"
+	case relocated:
+		result.Outcome = "restored as " + api.NamespacedKey(unstructuredObj.GetNamespace(), unstructuredObj.GetName())
+	default:
+		result.Outcome = "restored"
+	}
+	result.Outcome += dryRunSuffix
+	if len(dryRun) > 0 {
+		return result
+	}
+	// krb-controller counts the restore on the RecyclePolicy once the
+	// recycle item is deleted
+	markRestored(recycleItem)
+
+	if relocated {
+		// the original object is not restored, keep the recycle item
+		return result
+	}
+	// delete the recycle item after successful restore
+	if err := krbclient.RecycleItem().Delete(context.Background(), recycleItem.Name, client.DeleteOptions{}); err != nil {
+		tlog.Printf("✗ failed to automatically delete RecycleItem [%s] after restore: %v", recycleItem.Name, err)
+	} else {
+		tlog.Printf("✓ automatically deleted RecycleItem [%s] after restore.", recycleItem.Name)
+	}
+	return result
+}
+
+// markRestored labels the recycle item of a RecyclePolicy as restored.
+func markRestored(recycleItem *api.RecycleItem) {
+	if _, ok := recycleItem.Labels[api.RecyclePolicyLabel]; !ok || recycleItem.Labels[api.RestoredLabel] == "true" {
+		return
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest, err := krbclient.RecycleItem().Get(context.Background(), recycleItem.Name, client.GetOptions{})
+		if err != nil {
+			return err
+		}
+		latest.Labels[api.RestoredLabel] = "true"
+		return krbclient.RecycleItem().Update(context.Background(), latest, client.UpdateOptions{})
+	})
+	if err != nil {
+		tlog.Printf("✗ failed to mark RecycleItem [%s] as restored: %v", recycleItem.Name, err)
+	}
+}
+
+// registerSanitizeRules registers the sanitize rules from the krb-sanitize-rules
+// ConfigMap and the --sanitize flags in addition to the built-in ones.
+func registerSanitizeRules(sanitizeRules []string) {
+	cm, err := kube.Client().CoreV1().ConfigMaps(consts.WebhookNamespace).Get(context.Background(), consts.SanitizeRulesConfigMapName, metav1.GetOptions{})
+	switch {
+	case err == nil:
+		rules, err := api.ParseSanitizeRules([]byte(cm.Data[consts.SanitizeRulesConfigMapKey]))
+		if err != nil {
+			tlog.Panicf("✗ failed to parse sanitize rules from ConfigMap [%s/%s]: %v", consts.WebhookNamespace, consts.SanitizeRulesConfigMapName, err)
+		}
+		api.RegisterSanitizeRules(rules...)
+	case !k8serrors.IsNotFound(err):
+		tlog.Printf("✗ failed to get sanitize rules from ConfigMap [%s/%s]: %v, ignored.", consts.WebhookNamespace, consts.SanitizeRulesConfigMapName, err)
+	}
+
+	for _, s := range sanitizeRules {
+		rule, err := api.ParseSanitizeRule(s)
+		if err != nil {
+			tlog.Panicf("✗ %v", err)
+		}
+		api.RegisterSanitizeRules(rule)
+	}
+}
+
+// fixOwnerReferences points owner references at the live owners of the same
+// name, which are usually restored before, and drops those whose owner is gone.
+func fixOwnerReferences(obj *unstructured.Unstructured) {
+	var ownerReferences []metav1.OwnerReference
+	for _, owner := range obj.GetOwnerReferences() {
+		gv, err := schema.ParseGroupVersion(owner.APIVersion)
+		if err != nil {
+			continue
+		}
+		gvr, err := kube.GetGroupVersionResourceFromGroupVersionKind(gv.WithKind(owner.Kind))
+		if err != nil {
+			tlog.Printf("✗ failed to get resource of owner [%s: %s]: %v, owner reference dropped.", owner.Kind, owner.Name, err)
+			continue
+		}
+
+		// Owners are either in the same namespace or cluster-scoped.
+		live, err := kube.DynamicClient().Resource(gvr).Namespace(obj.GetNamespace()).Get(context.Background(), owner.Name, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) && obj.GetNamespace() != "" {
+			live, err = kube.DynamicClient().Resource(gvr).Get(context.Background(), owner.Name, metav1.GetOptions{})
+		}
+		if err != nil {
+			tlog.Printf("» owner [%s: %s] of [%s] not found, owner reference dropped.", owner.Kind, owner.Name, obj.GetName())
+			continue
+		}
+		owner.UID = live.GetUID()
+		ownerReferences = append(ownerReferences, owner)
+	}
+	obj.SetOwnerReferences(ownerReferences)
+}
+
+// printRestoreSummary prints which conflict strategy applied to each
+// RecycleItem and what the outcome was.
+func printRestoreSummary(results []restoreResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	t.AppendHeader(table.Row{"Recycle Item", "Object", "Conflict Strategy", "Outcome"})
+	for _, result := range results {
+		t.AppendRow(table.Row{result.RecycleItem, result.Object, util.If(result.Strategy == "", "-", result.Strategy), result.Outcome})
+	}
+	t.SetStyle(KrbTableStyle)
+	tlog.Println("")
+	t.Render()
 }
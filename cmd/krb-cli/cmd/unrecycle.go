@@ -0,0 +1,145 @@
+/*
+Copyright © 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package cmd
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
+	"github.com/ketches/kube-recycle-bin/internal/completion"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type UnrecycleFlags struct {
+	TargetNamespaces []string
+}
+
+var unrecycleFlags UnrecycleFlags
+
+// unrecycleCmd represents the unrecycle command
+var unrecycleCmd = &cobra.Command{
+	Use:   "unrecycle",
+	Short: "Stop recycling specified resources",
+	Long:  `Stop recycling specified resources. This command deletes the RecyclePolicies for the specified resource types, or removes namespaces from them.`,
+	Example: `# Stop recycling deployments
+krb-cli unrecycle deployments
+
+# Stop recycling deployments and services in dev namespace only
+krb-cli unrecycle deployments services -n dev
+`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		runUnrecycle(args)
+	},
+	ValidArgsFunction: completion.RecyclePolicyGroupResource,
+}
+
+func init() {
+	rootCmd.AddCommand(unrecycleCmd)
+
+	unrecycleCmd.Flags().StringSliceVarP(&unrecycleFlags.TargetNamespaces, "target-namespaces", "n", []string{}, "Only stop recycling in the specified namespaces, removing them from the RecyclePolicies")
+	unrecycleCmd.RegisterFlagCompletionFunc("target-namespaces", completion.RecyclePolicyNamespace)
+}
+
+func runUnrecycle(args []string) {
+	if len(args) == 0 {
+		tlog.Panicf("✗ please specify a resource to stop recycling.")
+	}
+
+	for _, resource := range args {
+		gvr, err := kube.GetPreferredGroupVersionResourceFor(resource)
+		if err != nil {
+			tlog.Errorf("✗ failed to get gvr from resource name: %v, ignored.", err)
+			continue
+		}
+		if gvr == nil {
+			tlog.Errorf("✗ no resources found for %s, ignored.", resource)
+			continue
+		}
+
+		list, err := krbclient.RecyclePolicy().List(context.Background(), client.ListOptions{
+			LabelSelector: labels.SelectorFromSet(labels.Set{api.TargetGroupResourceLabel: gvr.GroupResource().String()}),
+		})
+		if err != nil {
+			tlog.Panicf("✗ failed to list RecyclePolicy: %v", err)
+		}
+		if len(list.Items) == 0 {
+			tlog.Printf("» no recycle policies found for %s.", gvr.GroupResource().String())
+			continue
+		}
+
+		for i := range list.Items {
+			policy := &list.Items[i]
+			if len(unrecycleFlags.TargetNamespaces) == 0 {
+				deleteRecyclePolicy(policy.Name)
+				continue
+			}
+			removeRecyclePolicyNamespaces(policy, unrecycleFlags.TargetNamespaces)
+		}
+	}
+}
+
+// deleteRecyclePolicy deletes the RecyclePolicy, the webhook stops recycling
+// its target once the controller reconciles the deletion.
+func deleteRecyclePolicy(name string) {
+	if err := krbclient.RecyclePolicy().Delete(context.Background(), name, client.DeleteOptions{}); err != nil {
+		tlog.Printf("✗ failed to delete recycle policy [%s]: %v", name, err)
+		return
+	}
+	tlog.Printf("✓ delete recycle policy [%s] done.", name)
+}
+
+// removeRecyclePolicyNamespaces removes the namespaces from the RecyclePolicy,
+// and deletes it when no namespaces are left. A RecyclePolicy for all
+// namespaces can't exclude single namespaces and is left unchanged.
+func removeRecyclePolicyNamespaces(policy *api.RecyclePolicy, namespaces []string) {
+	if len(policy.Target.Namespaces) == 0 {
+		tlog.Printf("✗ recycle policy [%s] targets all namespaces, namespaces can't be removed from it, ignored.", policy.Name)
+		return
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(policy.Target.Namespaces), func(ns string) bool {
+		return slices.Contains(namespaces, ns)
+	})
+	switch {
+	case len(remaining) == len(policy.Target.Namespaces):
+		tlog.Printf("» recycle policy [%s] doesn't target the namespaces, skipped.", policy.Name)
+	case len(remaining) == 0:
+		deleteRecyclePolicy(policy.Name)
+	default:
+		updateRecyclePolicyNamespaces(policy, remaining)
+	}
+}
+
+// updateRecyclePolicyNamespaces updates the target namespaces of the
+// RecyclePolicy together with its target namespace labels.
+func updateRecyclePolicyNamespaces(policy *api.RecyclePolicy, namespaces []string) {
+	policy.SetTargetNamespaces(namespaces)
+	if err := krbclient.RecyclePolicy().Update(context.Background(), policy, client.UpdateOptions{}); err != nil {
+		tlog.Printf("✗ failed to update recycle policy [%s]: %v", policy.Name, err)
+		return
+	}
+	tlog.Printf("✓ update recycle policy [%s] target namespaces to [%s] done.", policy.Name, util.If(len(policy.Target.Namespaces) == 0, "*", strings.Join(policy.Target.Namespaces, ",")))
+}
@@ -18,18 +18,28 @@ package cmd
 
 import (
 	"context"
+	"fmt"
+	"strings"
 
+	"github.com/ketches/kube-recycle-bin/internal/api"
 	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
 	"github.com/ketches/kube-recycle-bin/internal/completion"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
 	"github.com/spf13/cobra"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const redactedValue = "REDACTED"
+
+var secretGroupKind = schema.GroupKind{Kind: "Secret"}
+
 type ViewFlags struct {
 	ObjectResource  string
 	ObjectNamespace string
 	OutputFormat    string
+	ShowSecrets     bool
 }
 
 var viewFlags ViewFlags
@@ -50,6 +60,9 @@ krb-cli view --object-namespace dev foo-deploy foo-svc
 
 # View recycled resource objects from RecycleItem with names foo and bar in JSON format
 krb-cli view foo bar --output json
+
+# View the data of a recycled secret, which is redacted by default
+krb-cli view my-secret-skk5c89b --show-secrets
 `,
 	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
@@ -64,6 +77,7 @@ func init() {
 	viewCmd.Flags().StringVarP(&viewFlags.ObjectResource, "object-resource", "", "", "View recycled resource objects filtered by the specified object resource")
 	viewCmd.Flags().StringVarP(&viewFlags.ObjectNamespace, "object-namespace", "", "", "View recycled resource objects filtered by the specified object namespace")
 	viewCmd.Flags().StringVarP(&viewFlags.OutputFormat, "output", "o", "yaml", "Output format. One of: json|yaml, default is yaml")
+	viewCmd.Flags().BoolVar(&viewFlags.ShowSecrets, "show-secrets", false, "Show the data of recycled secrets and encrypted objects instead of redacting it")
 
 	viewCmd.RegisterFlagCompletionFunc("object-resource", completion.RecycleItemGroupResource)
 	viewCmd.RegisterFlagCompletionFunc("object-namespace", completion.RecycleItemNamespace)
@@ -84,6 +98,17 @@ func runView(args []string) {
 			tlog.Printf("✗ failed to get RecycleItem [%s]: %v, ignored.", recycleItemName, err)
 			continue
 		}
+		encrypted := recycleItem.Object.Encryption != nil
+		if err := krbclient.LoadPayload(context.Background(), recycleItem); err != nil {
+			tlog.Printf("✗ failed to load payload of RecycleItem [%s]: %v, ignored.", recycleItemName, err)
+			continue
+		}
+		if !viewFlags.ShowSecrets && (encrypted || recycleItem.Object.ObjectGroupKind() == secretGroupKind) {
+			if err := redactData(&recycleItem.Object); err != nil {
+				tlog.Printf("✗ failed to redact recycled resource object [%s: %s] from RecycleItem [%s]: %v, ignored.", recycleItem.Object.GroupResource().String(), recycleItem.Object.Key(), recycleItem.Name, err)
+				continue
+			}
+		}
 
 		switch viewFlags.OutputFormat {
 		case "json":
@@ -93,7 +118,10 @@ func runView(args []string) {
 				continue
 			}
 
-			tlog.Printf("» [%s: %s]\n", recycleItem.Object.GroupResource().String(), recycleItem.Object.Key())
+			tlog.Printf("» [%s: %s]", recycleItem.Object.GroupResource().String(), recycleItem.Object.Key())
+			for _, line := range deletionSummary(recycleItem.Deletion) {
+				tlog.Printf("» %s", line)
+			}
 			tlog.Println(objContent)
 		default:
 			objContent, err := recycleItem.Object.YAML()
@@ -106,7 +134,60 @@ func runView(args []string) {
 			} else {
 				tlog.Printf("---")
 			}
+			for _, line := range deletionSummary(recycleItem.Deletion) {
+				tlog.Printf("# %s", line)
+			}
 			tlog.Print(objContent)
 		}
 	}
 }
+
+// redactData replaces the values of the data fields of the recycled object,
+// and the last applied configuration holding them, with a placeholder.
+func redactData(recycledObj *api.RecycledObject) error {
+	obj, err := recycledObj.Unstructured()
+	if err != nil {
+		return err
+	}
+
+	for _, field := range []string{"data", "stringData", "binaryData"} {
+		values, ok := obj.Object[field].(map[string]any)
+		if !ok {
+			continue
+		}
+		for key := range values {
+			values[key] = redactedValue
+		}
+	}
+	if annotations := obj.GetAnnotations(); annotations[corev1.LastAppliedConfigAnnotation] != "" {
+		annotations[corev1.LastAppliedConfigAnnotation] = redactedValue
+		obj.SetAnnotations(annotations)
+	}
+
+	data, err := obj.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return recycledObj.SetData(data, api.EncodingNone)
+}
+
+// deletionSummary describes who deleted the recycled object and how.
+func deletionSummary(deletion *api.DeletionInfo) []string {
+	if deletion == nil {
+		return nil
+	}
+
+	deletedBy := deletion.Username
+	if len(deletion.Groups) > 0 {
+		deletedBy += " (groups: " + strings.Join(deletion.Groups, ",") + ")"
+	}
+	result := []string{"Deleted by: " + deletedBy}
+	if deletion.ServiceAccount != "" {
+		result = append(result, "Service account: "+deletion.ServiceAccount)
+	}
+	if deletion.PropagationPolicy != nil || deletion.GracePeriodSeconds != nil {
+		result = append(result, fmt.Sprintf("Delete options: propagationPolicy=%s, gracePeriod=%s", deletionPropagationPolicy(deletion), deletionGracePeriod(deletion)))
+	}
+	result = append(result, "Deleted at: "+deletionTime(deletion), "Admission UID: "+string(deletion.AdmissionUID))
+	return result
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+)
+
+func TestRedactData(t *testing.T) {
+	testdata := []struct {
+		name    string
+		obj     string
+		desired string
+	}{
+		{
+			name: "secret",
+			obj: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "namespace": "dev", "annotations": {
+					"kubectl.kubernetes.io/last-applied-configuration": "{\"data\":{\"password\":\"cGFzc3dvcmQ=\"}}", "team": "db"}},
+				"type": "Opaque", "data": {"password": "cGFzc3dvcmQ=", "username": "YWRtaW4="}}`,
+			desired: `{"apiVersion": "v1", "kind": "Secret",
+				"metadata": {"name": "db", "namespace": "dev", "annotations": {
+					"kubectl.kubernetes.io/last-applied-configuration": "REDACTED", "team": "db"}},
+				"type": "Opaque", "data": {"password": "REDACTED", "username": "REDACTED"}}`,
+		},
+		{
+			name:    "configmap",
+			obj:     `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "app"}, "data": {"key": "value"}, "binaryData": {"bin": "AAE="}}`,
+			desired: `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "app"}, "data": {"key": "REDACTED"}, "binaryData": {"bin": "REDACTED"}}`,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			recycledObj := &api.RecycledObject{}
+			if err := recycledObj.SetData([]byte(tt.obj), api.EncodingGzip); err != nil {
+				t.Fatalf("✗ failed to set data: %v", err)
+			}
+			if err := redactData(recycledObj); err != nil {
+				t.Fatalf("✗ failed to redact: %v", err)
+			}
+
+			data, err := recycledObj.Data()
+			if err != nil {
+				t.Fatalf("✗ failed to get data: %v", err)
+			}
+			var got, desired map[string]any
+			json.Unmarshal(data, &got)
+			json.Unmarshal([]byte(tt.desired), &desired)
+			if !reflect.DeepEqual(got, desired) {
+				t.Errorf("✗ expected %v, got %v", desired, got)
+			}
+		})
+	}
+}
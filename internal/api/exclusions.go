@@ -0,0 +1,127 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+)
+
+// ErrExcluded is returned for targets that are excluded from recycling.
+var ErrExcluded = errors.New("excluded from recycling")
+
+// Exclusions are resources and namespaces that are never recycled.
+type Exclusions struct {
+	// Resources are excluded group resources, a "*" resource excludes all
+	// resources of the group.
+	Resources []schema.GroupResource
+	// Namespaces are excluded namespaces.
+	Namespaces []string
+}
+
+// BuiltinExclusions keep krb from recycling its own RecycleItems and
+// RecyclePolicies, the objects in its own namespace such as the webhook TLS
+// Secret, control-plane objects and leases, which are deleted all the time.
+var BuiltinExclusions = Exclusions{
+	Resources: []schema.GroupResource{
+		{Group: Group, Resource: "*"},
+		{Group: "coordination.k8s.io", Resource: "leases"},
+	},
+	Namespaces: []string{
+		consts.WebhookNamespace,
+		metav1.NamespaceSystem,
+	},
+}
+
+// NewExclusions returns the built-in exclusions together with the resources,
+// in the form of resource.group such as leases.coordination.k8s.io, and the
+// namespaces.
+func NewExclusions(resources, namespaces []string) Exclusions {
+	result := Exclusions{
+		Resources:  slices.Clone(BuiltinExclusions.Resources),
+		Namespaces: slices.Clone(BuiltinExclusions.Namespaces),
+	}
+	for _, resource := range resources {
+		if resource = strings.TrimSpace(resource); resource != "" {
+			result.Resources = append(result.Resources, schema.ParseGroupResource(resource))
+		}
+	}
+	for _, namespace := range namespaces {
+		if namespace = strings.TrimSpace(namespace); namespace != "" && !slices.Contains(result.Namespaces, namespace) {
+			result.Namespaces = append(result.Namespaces, namespace)
+		}
+	}
+	return result
+}
+
+// LoadExclusions returns the built-in exclusions together with the resources
+// and namespaces listed in the krb-exclusions ConfigMap in krb-system, which
+// krb-controller and krb-webhook both load so they exclude the same objects.
+// Only the built-in exclusions apply if there is no ConfigMap.
+func LoadExclusions(ctx context.Context, client kubernetes.Interface) (Exclusions, error) {
+	configMap, err := client.CoreV1().ConfigMaps(consts.WebhookNamespace).Get(ctx, consts.ExclusionsConfigMapName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return NewExclusions(nil, nil), nil
+	}
+	if err != nil {
+		return Exclusions{}, fmt.Errorf("failed to get configmap [%s]: %w", consts.ExclusionsConfigMapName, err)
+	}
+	// lists are separated by commas or whitespace such as new lines
+	split := func(s string) []string {
+		return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || unicode.IsSpace(r) })
+	}
+	return NewExclusions(split(configMap.Data[consts.ExclusionsResourcesKey]), split(configMap.Data[consts.ExclusionsNamespacesKey])), nil
+}
+
+// ExcludesResource reports whether the group resource is excluded.
+func (e *Exclusions) ExcludesResource(gr schema.GroupResource) bool {
+	return slices.ContainsFunc(e.Resources, func(excluded schema.GroupResource) bool {
+		return excluded.Group == gr.Group && (excluded.Resource == "*" || excluded.Resource == gr.Resource)
+	})
+}
+
+// ExcludesNamespace reports whether the namespace is excluded.
+func (e *Exclusions) ExcludesNamespace(namespace string) bool {
+	return namespace != "" && slices.Contains(e.Namespaces, namespace)
+}
+
+// Validate returns an error if the target lists an excluded resource or an
+// excluded namespace. Excluded resources of "*" resources and categories are
+// left out when the target is resolved instead.
+func (e *Exclusions) Validate(target *RecycleTarget) error {
+	for _, tr := range target.TargetResources() {
+		if e.ExcludesResource(tr.GroupResource()) {
+			return fmt.Errorf("resource %s is %w", tr.GroupResource().String(), ErrExcluded)
+		}
+	}
+	for _, namespace := range target.Namespaces {
+		if e.ExcludesNamespace(namespace) {
+			return fmt.Errorf("namespace %s is %w", namespace, ErrExcluded)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExclusionsValidate(t *testing.T) {
+	exclusions := NewExclusions([]string{"events", "secrets.", " "}, []string{"monitoring"})
+
+	testdata := []struct {
+		name     string
+		target   RecycleTarget
+		excluded bool
+	}{
+		{
+			name:   "deployments",
+			target: RecycleTarget{Group: "apps", Resource: "deployments", Namespaces: []string{"dev"}},
+		},
+		{
+			name:     "recycle-items",
+			target:   RecycleTarget{Group: Group, Resource: "recycleitems"},
+			excluded: true,
+		},
+		{
+			name:     "leases",
+			target:   RecycleTarget{Group: "coordination.k8s.io", Resource: "leases"},
+			excluded: true,
+		},
+		{
+			name:     "configured-resource",
+			target:   RecycleTarget{Resource: "events"},
+			excluded: true,
+		},
+		{
+			name:     "configured-resource-with-trailing-dot",
+			target:   RecycleTarget{Resource: "secrets"},
+			excluded: true,
+		},
+		{
+			name:     "krb-system",
+			target:   RecycleTarget{Resource: "configmaps", Namespaces: []string{"dev", "krb-system"}},
+			excluded: true,
+		},
+		{
+			name:     "configured-namespace",
+			target:   RecycleTarget{Resource: "configmaps", Namespaces: []string{"monitoring"}},
+			excluded: true,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			err := exclusions.Validate(&tt.target)
+			if excluded := errors.Is(err, ErrExcluded); excluded != tt.excluded {
+				t.Errorf("✗ expected excluded %v, got error %v", tt.excluded, err)
+			}
+		})
+	}
+}
+
+func TestLoadExclusions(t *testing.T) {
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: consts.ExclusionsConfigMapName, Namespace: consts.WebhookNamespace},
+		Data: map[string]string{
+			consts.ExclusionsResourcesKey:  "events.events.k8s.io,events",
+			consts.ExclusionsNamespacesKey: "kube-public\nkube-node-lease\n",
+		},
+	}
+
+	exclusions, err := LoadExclusions(context.Background(), fake.NewClientset(configMap))
+	if err != nil {
+		t.Fatalf("✗ failed to load exclusions: %v", err)
+	}
+	desired := NewExclusions([]string{"events.events.k8s.io", "events"}, []string{"kube-public", "kube-node-lease"})
+	if !reflect.DeepEqual(exclusions, desired) {
+		t.Errorf("✗ expected %v, got %v", desired, exclusions)
+	}
+	if !exclusions.ExcludesResource(schema.GroupResource{Resource: "events"}) {
+		t.Errorf("✗ expected events to be excluded")
+	}
+
+	exclusions, err = LoadExclusions(context.Background(), fake.NewClientset())
+	if err != nil {
+		t.Fatalf("✗ failed to load exclusions without configmap: %v", err)
+	}
+	if !reflect.DeepEqual(exclusions, NewExclusions(nil, nil)) {
+		t.Errorf("✗ expected the built-in exclusions, got %v", exclusions)
+	}
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+)
+
+const (
+	// EncodingNone stores Raw as plain JSON, as earlier versions did.
+	EncodingNone = ""
+	// EncodingGzip stores Raw as gzip compressed JSON.
+	EncodingGzip = "gzip"
+)
+
+// ErrOffloaded is returned when the payload of a recycled object is accessed
+// before it is loaded from where it was offloaded to.
+var ErrOffloaded = errors.New("payload is offloaded, load it first")
+
+// ErrEncrypted is returned when the payload of a recycled object is accessed
+// before it is decrypted.
+var ErrEncrypted = errors.New("payload is encrypted, decrypt it first")
+
+// ErrPayloadMismatch is returned when a loaded payload doesn't match its
+// reference.
+var ErrPayloadMismatch = errors.New("payload doesn't match its reference")
+
+// ParseEncoding parses the encoding of recycled objects, "none" is accepted
+// for no encoding.
+func ParseEncoding(s string) (string, error) {
+	switch s {
+	case EncodingNone, "none":
+		return EncodingNone, nil
+	case EncodingGzip:
+		return EncodingGzip, nil
+	}
+	return "", fmt.Errorf("invalid encoding %q, must be one of: none|gzip", s)
+}
+
+// Data returns the JSON of the recycled object, decoding Raw.
+func (obj *RecycledObject) Data() ([]byte, error) {
+	if len(obj.Raw) == 0 && obj.Ref != nil {
+		return nil, ErrOffloaded
+	}
+	if obj.Encryption != nil {
+		return nil, ErrEncrypted
+	}
+
+	switch obj.Encoding {
+	case EncodingNone:
+		return obj.Raw, nil
+	case EncodingGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(obj.Raw))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	}
+	return nil, fmt.Errorf("unknown encoding %q", obj.Encoding)
+}
+
+// SetData encodes the JSON of the recycled object into Raw.
+func (obj *RecycledObject) SetData(data []byte, encoding string) error {
+	switch encoding {
+	case EncodingNone:
+		obj.Raw = data
+	case EncodingGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		if _, err := writer.Write(data); err != nil {
+			return err
+		}
+		if err := writer.Close(); err != nil {
+			return err
+		}
+		obj.Raw = buf.Bytes()
+	default:
+		return fmt.Errorf("unknown encoding %q", encoding)
+	}
+	obj.Encoding = encoding
+	return nil
+}
+
+// Truncate keeps only the type and metadata of the recycled object, marking
+// it as truncated. Truncated objects can't be restored.
+func (obj *RecycledObject) Truncate() error {
+	data, err := obj.Data()
+	if err != nil {
+		return err
+	}
+
+	var truncated struct {
+		APIVersion string          `json:"apiVersion,omitempty"`
+		Kind       string          `json:"kind,omitempty"`
+		Metadata   json.RawMessage `json:"metadata,omitempty"`
+	}
+	if err := json.Unmarshal(data, &truncated); err != nil {
+		return err
+	}
+	data, err = json.Marshal(truncated)
+	if err != nil {
+		return err
+	}
+	obj.Truncated = true
+	return obj.SetData(data, obj.Encoding)
+}
+
+// PayloadRef returns the reference to the payload of the RecycleItem in a
+// storage backend, nil if the payload is stored in the RecycleItem.
+func (ri *RecycleItem) PayloadRef() *PayloadRef {
+	return ri.Object.Ref
+}
+
+// Offload moves the encoded payload of the RecycleItem to a reference into
+// the storage backend, keyed by the name of the RecycleItem, and adds the
+// PayloadFinalizer so the payload is deleted together with the RecycleItem.
+// It returns the payload to store.
+func (ri *RecycleItem) Offload(backend string) []byte {
+	payload := ri.Object.Raw
+	digest := sha256.Sum256(payload)
+	ri.Object.Raw = nil
+	ri.Object.Ref = &PayloadRef{
+		Backend: backend,
+		Key:     ri.Name,
+		Size:    len(payload),
+		SHA256:  hex.EncodeToString(digest[:]),
+	}
+	if !slices.Contains(ri.Finalizers, PayloadFinalizer) {
+		ri.Finalizers = append(ri.Finalizers, PayloadFinalizer)
+	}
+	return payload
+}
+
+// Load sets the payload of the recycled object loaded from the storage
+// backend, verifying it against the reference.
+func (obj *RecycledObject) Load(payload []byte) error {
+	if obj.Ref != nil {
+		digest := sha256.Sum256(payload)
+		if (obj.Ref.Size > 0 && len(payload) != obj.Ref.Size) || (obj.Ref.SHA256 != "" && hex.EncodeToString(digest[:]) != obj.Ref.SHA256) {
+			return fmt.Errorf("%w: %s/%s", ErrPayloadMismatch, obj.Ref.Backend, obj.Ref.Key)
+		}
+	}
+	obj.Raw = payload
+	return nil
+}
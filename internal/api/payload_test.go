@@ -0,0 +1,114 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"testing"
+)
+
+const payloadTestData = `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"app","namespace":"dev"},"data":{"key":"value"}}`
+
+func TestRecycledObjectData(t *testing.T) {
+	testdata := []struct {
+		name     string
+		encoding string
+	}{
+		{name: "none", encoding: EncodingNone},
+		{name: "gzip", encoding: EncodingGzip},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &RecycledObject{}
+			if err := obj.SetData([]byte(payloadTestData), tt.encoding); err != nil {
+				t.Fatalf("✗ failed to set data: %v", err)
+			}
+			if obj.Encoding != tt.encoding {
+				t.Errorf("✗ expected encoding %q, got %q", tt.encoding, obj.Encoding)
+			}
+			data, err := obj.Data()
+			if err != nil {
+				t.Fatalf("✗ failed to get data: %v", err)
+			}
+			if string(data) != payloadTestData {
+				t.Errorf("✗ expected %s, got %s", payloadTestData, data)
+			}
+		})
+	}
+
+	// objects recycled by earlier versions are plain JSON without encoding
+	legacy := &RecycledObject{Raw: []byte(payloadTestData)}
+	if meta, err := legacy.ObjectMeta(); err != nil || meta.Name != "app" {
+		t.Errorf("✗ expected metadata of legacy object, got %v, %v", meta, err)
+	}
+
+	offloaded := &RecycledObject{Encoding: EncodingGzip, Ref: &PayloadRef{Backend: "configmap", Key: "app-abcd1234"}}
+	if _, err := offloaded.Data(); !errors.Is(err, ErrOffloaded) {
+		t.Errorf("✗ expected %v, got %v", ErrOffloaded, err)
+	}
+}
+
+func TestRecycledObjectTruncate(t *testing.T) {
+	obj := &RecycledObject{}
+	if err := obj.SetData([]byte(payloadTestData), EncodingGzip); err != nil {
+		t.Fatalf("✗ failed to set data: %v", err)
+	}
+	if err := obj.Truncate(); err != nil {
+		t.Fatalf("✗ failed to truncate: %v", err)
+	}
+
+	data, err := obj.Data()
+	if err != nil {
+		t.Fatalf("✗ failed to get data: %v", err)
+	}
+	if desired := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"app","namespace":"dev"}}`; string(data) != desired {
+		t.Errorf("✗ expected %s, got %s", desired, data)
+	}
+	if !obj.Truncated {
+		t.Errorf("✗ expected object marked as truncated")
+	}
+}
+
+func TestRecycleItemOffload(t *testing.T) {
+	recycleItem := &RecycleItem{}
+	recycleItem.Name = "app-abcd1234"
+	if err := recycleItem.Object.SetData([]byte(payloadTestData), EncodingGzip); err != nil {
+		t.Fatalf("✗ failed to set data: %v", err)
+	}
+
+	payload := recycleItem.Offload("s3")
+	if ref := recycleItem.PayloadRef(); ref == nil || ref.Backend != "s3" || ref.Key != recycleItem.Name || ref.Size != len(payload) {
+		t.Errorf("✗ expected reference to the s3 backend, got %+v", ref)
+	}
+	if len(recycleItem.Finalizers) != 1 || recycleItem.Finalizers[0] != PayloadFinalizer {
+		t.Errorf("✗ expected finalizer %s, got %v", PayloadFinalizer, recycleItem.Finalizers)
+	}
+	if _, err := recycleItem.Object.Data(); !errors.Is(err, ErrOffloaded) {
+		t.Errorf("✗ expected %v, got %v", ErrOffloaded, err)
+	}
+
+	if err := recycleItem.Object.Load(append(payload[:len(payload):len(payload)], 0)); !errors.Is(err, ErrPayloadMismatch) {
+		t.Errorf("✗ expected %v, got %v", ErrPayloadMismatch, err)
+	}
+	if err := recycleItem.Object.Load(payload); err != nil {
+		t.Fatalf("✗ failed to load payload: %v", err)
+	}
+	if data, err := recycleItem.Object.Data(); err != nil || string(data) != payloadTestData {
+		t.Errorf("✗ expected %s, got %s, %v", payloadTestData, data, err)
+	}
+}
@@ -16,7 +16,10 @@ limitations under the License.
 
 package api
 
-import "k8s.io/apimachinery/pkg/runtime"
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
 
 func (in *RecycleItem) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
@@ -38,8 +41,57 @@ func (in *RecycleItem) DeepCopy() *RecycleItem {
 func (in *RecycleItem) DeepCopyInto(out *RecycleItem) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	out.ObjectMeta = in.ObjectMeta
-	out.Object = in.Object
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Object.DeepCopyInto(&out.Object)
+	if in.Deletion != nil {
+		out.Deletion = new(DeletionInfo)
+		in.Deletion.DeepCopyInto(out.Deletion)
+	}
+}
+
+func (in *DeletionInfo) DeepCopyInto(out *DeletionInfo) {
+	*out = *in
+	if in.Groups != nil {
+		out.Groups = make([]string, len(in.Groups))
+		copy(out.Groups, in.Groups)
+	}
+	if in.PropagationPolicy != nil {
+		out.PropagationPolicy = new(metav1.DeletionPropagation)
+		*out.PropagationPolicy = *in.PropagationPolicy
+	}
+	if in.GracePeriodSeconds != nil {
+		out.GracePeriodSeconds = new(int64)
+		*out.GracePeriodSeconds = *in.GracePeriodSeconds
+	}
+	in.DeletedAt.DeepCopyInto(&out.DeletedAt)
+}
+
+func (in *RecycledObject) DeepCopyInto(out *RecycledObject) {
+	*out = *in
+	if in.Raw != nil {
+		out.Raw = make([]byte, len(in.Raw))
+		copy(out.Raw, in.Raw)
+	}
+	if in.Ref != nil {
+		out.Ref = new(PayloadRef)
+		*out.Ref = *in.Ref
+	}
+	if in.Encryption != nil {
+		out.Encryption = new(EncryptionInfo)
+		in.Encryption.DeepCopyInto(out.Encryption)
+	}
+	if in.Redacted != nil {
+		out.Redacted = make([]RedactedField, len(in.Redacted))
+		copy(out.Redacted, in.Redacted)
+	}
+}
+
+func (in *EncryptionInfo) DeepCopyInto(out *EncryptionInfo) {
+	*out = *in
+	if in.EncryptedKey != nil {
+		out.EncryptedKey = make([]byte, len(in.EncryptedKey))
+		copy(out.EncryptedKey, in.EncryptedKey)
+	}
 }
 
 func (in *RecycleItemList) DeepCopyObject() runtime.Object {
@@ -20,19 +20,57 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strconv"
+	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/rand"
 	"sigs.k8s.io/yaml"
 )
 
+const (
+	// ObjectNameLabel, ObjectGroupResourceLabel and ObjectNamespaceLabel
+	// identify the recycled object of a RecycleItem.
+	ObjectNameLabel          = "krb.ketches.cn/object-name"
+	ObjectGroupResourceLabel = "krb.ketches.cn/object-gr"
+	ObjectNamespaceLabel     = "krb.ketches.cn/object-namespace"
+	// RecycledAtLabel records the unix time at which the object was recycled.
+	RecycledAtLabel = "krb.ketches.cn/recycled-at"
+	// RecyclePolicyLabel records the RecyclePolicy that recycled the object.
+	RecyclePolicyLabel = "krb.ketches.cn/recycle-policy"
+	// RecycleItemLabel records the RecycleItem whose payload a ConfigMap holds.
+	RecycleItemLabel = "krb.ketches.cn/recycle-item"
+	// RetentionAnnotation records how long the RecycleItem is kept before it
+	// is garbage collected, stamped from the RecyclePolicy at creation.
+	RetentionAnnotation = "krb.ketches.cn/retention"
+	// KeepAnnotation set to "true" keeps the RecycleItem forever.
+	KeepAnnotation = "krb.ketches.cn/keep"
+	// SkipAnnotation set to "true" on an object keeps it from being recycled.
+	SkipAnnotation = "krb.ketches.cn/skip"
+	// PayloadFinalizer keeps a RecycleItem until krb-controller has deleted
+	// its payload from the storage backend.
+	PayloadFinalizer = "krb.ketches.cn/payload"
+	// CountFinalizer keeps a RecycleItem recycled by a RecyclePolicy until
+	// krb-controller has counted it as restored or purged on the status of
+	// the policy.
+	CountFinalizer = "krb.ketches.cn/count"
+	// RestoredLabel set to "true" by krb-cli marks a RecycleItem whose object
+	// was restored.
+	RestoredLabel = "krb.ketches.cn/restored"
+)
+
 type RecycleItem struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
 	Object RecycledObject `json:"object"`
+
+	// Deletion records who deleted the object and how.
+	Deletion *DeletionInfo `json:"deletion,omitempty"`
 }
 
 type RecycledObject struct {
@@ -42,7 +80,67 @@ type RecycledObject struct {
 	Resource  string `json:"resource"`
 	Namespace string `json:"namespace,omitempty"`
 	Name      string `json:"name"`
-	Raw       []byte `json:"raw"`
+	// Encoding of Raw, empty for plain JSON.
+	Encoding string `json:"encoding,omitempty"`
+	// Raw is the encoded JSON of the object, empty if it is stored in a
+	// storage backend.
+	Raw []byte `json:"raw,omitempty"`
+	// Ref references the encoded JSON of the object in a storage backend.
+	Ref *PayloadRef `json:"ref,omitempty"`
+	// Encryption of Raw, nil if it is not encrypted.
+	Encryption *EncryptionInfo `json:"encryption,omitempty"`
+	// Truncated is true if only the type and metadata of the object are
+	// kept, because it was too large.
+	Truncated bool `json:"truncated,omitempty"`
+	// Redacted are the fields removed by the redact rules of the policy, the
+	// object is incomplete without them.
+	Redacted []RedactedField `json:"redacted,omitempty"`
+}
+
+// EncryptionInfo describes the envelope encryption of a payload. The payload
+// is encrypted with a random data key, which is encrypted with the key of the
+// provider.
+type EncryptionInfo struct {
+	// Provider of the key the data key is encrypted with.
+	Provider string `json:"provider"`
+	// KeyID identifies the key of the provider.
+	KeyID string `json:"keyID,omitempty"`
+	// EncryptedKey is the encrypted data key.
+	EncryptedKey []byte `json:"encryptedKey"`
+}
+
+// PayloadRef references the payload of a recycled object in a storage
+// backend.
+type PayloadRef struct {
+	// Backend the payload is stored in.
+	Backend string `json:"backend"`
+	// Key of the payload in the backend.
+	Key string `json:"key"`
+	// Size of the payload in bytes.
+	Size int `json:"size,omitempty"`
+	// SHA256 is the hex encoded SHA-256 digest of the payload.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// DeletionInfo is taken from the admission request of the deletion.
+type DeletionInfo struct {
+	// Username is the name of the user who deleted the object.
+	Username string `json:"username,omitempty"`
+	// UserUID is the UID of the user who deleted the object.
+	UserUID string `json:"userUID,omitempty"`
+	// Groups are the groups of the user who deleted the object.
+	Groups []string `json:"groups,omitempty"`
+	// ServiceAccount is the namespace/name of the service account that
+	// deleted the object, if the user is a service account.
+	ServiceAccount string `json:"serviceAccount,omitempty"`
+	// PropagationPolicy is the propagation policy of the delete options.
+	PropagationPolicy *metav1.DeletionPropagation `json:"propagationPolicy,omitempty"`
+	// GracePeriodSeconds is the grace period of the delete options.
+	GracePeriodSeconds *int64 `json:"gracePeriodSeconds,omitempty"`
+	// AdmissionUID is the UID of the admission request of the deletion.
+	AdmissionUID types.UID `json:"admissionUID,omitempty"`
+	// DeletedAt is the time at which the deletion was admitted.
+	DeletedAt metav1.Time `json:"deletedAt"`
 }
 
 type RecycleItemList struct {
@@ -53,12 +151,12 @@ type RecycleItemList struct {
 
 func NewRecycleItem(recycledObj *RecycledObject) *RecycleItem {
 	labels := map[string]string{
-		"krb.ketches.cn/object-name": recycledObj.Name,
-		"krb.ketches.cn/object-gr":   recycledObj.GroupResource().String(),
-		"krb.ketches.cn/recycled-at": fmt.Sprintf("%d", metav1.Now().Unix()),
+		ObjectNameLabel:          recycledObj.Name,
+		ObjectGroupResourceLabel: recycledObj.GroupResource().String(),
+		RecycledAtLabel:          fmt.Sprintf("%d", metav1.Now().Unix()),
 	}
 	if recycledObj.Namespace != "" {
-		labels["krb.ketches.cn/object-namespace"] = recycledObj.Namespace
+		labels[ObjectNamespaceLabel] = recycledObj.Namespace
 	}
 
 	return &RecycleItem{
@@ -74,11 +172,72 @@ func NewRecycleItem(recycledObj *RecycledObject) *RecycleItem {
 	}
 }
 
+// ApplyPolicy stamps the name and retention of the RecyclePolicy that
+// recycled the object onto the RecycleItem, and adds the CountFinalizer so
+// its removal is counted on the status of the policy.
+func (ri *RecycleItem) ApplyPolicy(policy *RecyclePolicy) {
+	if ri.Labels == nil {
+		ri.Labels = map[string]string{}
+	}
+	ri.Labels[RecyclePolicyLabel] = policy.Name
+	if !slices.Contains(ri.Finalizers, CountFinalizer) {
+		ri.Finalizers = append(ri.Finalizers, CountFinalizer)
+	}
+
+	if policy.Retention != nil {
+		if ri.Annotations == nil {
+			ri.Annotations = map[string]string{}
+		}
+		ri.Annotations[RetentionAnnotation] = policy.Retention.Duration.String()
+	}
+}
+
+// RecycledAt returns the time at which the object was recycled.
+func (ri *RecycleItem) RecycledAt() (time.Time, error) {
+	sec, err := strconv.ParseInt(ri.Labels[RecycledAtLabel], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid label %s: %w", RecycledAtLabel, err)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// ExpireAt returns the time at which the RecycleItem expires, falling back to
+// defaultRetention when no retention was stamped onto it. The returned bool
+// is false if the RecycleItem never expires.
+func (ri *RecycleItem) ExpireAt(defaultRetention time.Duration) (time.Time, bool, error) {
+	if ri.Annotations[KeepAnnotation] == "true" {
+		return time.Time{}, false, nil
+	}
+
+	retention := defaultRetention
+	if v, ok := ri.Annotations[RetentionAnnotation]; ok {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid annotation %s: %w", RetentionAnnotation, err)
+		}
+		retention = d
+	}
+	if retention <= 0 {
+		return time.Time{}, false, nil
+	}
+
+	recycledAt, err := ri.RecycledAt()
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return recycledAt.Add(retention), true, nil
+}
+
 func (obj *RecycledObject) Key() string {
-	if obj.Namespace == "" {
-		return obj.Name
+	return NamespacedKey(obj.Namespace, obj.Name)
+}
+
+// NamespacedKey returns namespace/name, or name for cluster-scoped objects.
+func NamespacedKey(namespace, name string) string {
+	if namespace == "" {
+		return name
 	}
-	return obj.Namespace + "/" + obj.Name
+	return namespace + "/" + name
 }
 
 func (obj *RecycledObject) GroupVersionKind() schema.GroupVersionKind {
@@ -119,25 +278,50 @@ func (obj *RecycledObject) ObjectGroupKind() schema.GroupKind {
 }
 
 func (obj *RecycledObject) Unstructured() (*unstructured.Unstructured, error) {
+	data, err := obj.Data()
+	if err != nil {
+		return nil, err
+	}
 	unstructuredObj := &unstructured.Unstructured{}
-	if err := json.Unmarshal(obj.Raw, unstructuredObj); err != nil {
+	if err := json.Unmarshal(data, unstructuredObj); err != nil {
 		return nil, err
 	}
 
-	// Remove the resourceVersion field from the metadata, so it
-	// doesn't cause conflicts when creating a new object.
-	unstructured.RemoveNestedField(unstructuredObj.Object, "metadata", "resourceVersion")
+	// Remove server-populated fields such as resourceVersion, so they
+	// don't cause conflicts when creating a new object.
+	Sanitize(unstructuredObj)
 
 	return unstructuredObj, nil
 }
 
-func (obj *RecycledObject) JSON() string {
-	return string(obj.Raw)
+// ObjectMeta returns the metadata of the recycled object.
+func (obj *RecycledObject) ObjectMeta() (*metav1.ObjectMeta, error) {
+	data, err := obj.Data()
+	if err != nil {
+		return nil, err
+	}
+	var partial metav1.PartialObjectMetadata
+	if err := json.Unmarshal(data, &partial); err != nil {
+		return nil, err
+	}
+	return &partial.ObjectMeta, nil
+}
+
+func (obj *RecycledObject) JSON() (string, error) {
+	data, err := obj.Data()
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
 
 func (obj *RecycledObject) IndentedJSON() (string, error) {
+	data, err := obj.Data()
+	if err != nil {
+		return "", err
+	}
 	var out bytes.Buffer
-	if err := json.Indent(&out, obj.Raw, "", "  "); err != nil {
+	if err := json.Indent(&out, data, "", "  "); err != nil {
 		return "", err
 	}
 
@@ -145,7 +329,11 @@ func (obj *RecycledObject) IndentedJSON() (string, error) {
 }
 
 func (obj *RecycledObject) YAML() (string, error) {
-	b, err := yaml.JSONToYAML(obj.Raw)
+	data, err := obj.Data()
+	if err != nil {
+		return "", err
+	}
+	b, err := yaml.JSONToYAML(data)
 	if err != nil {
 		return "", err
 	}
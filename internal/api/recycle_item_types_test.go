@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecycleItemExpireAt(t *testing.T) {
+	recycledAt := time.Unix(1700000000, 0)
+	defaultRetention := time.Hour * 24
+
+	testdata := []struct {
+		name        string
+		annotations map[string]string
+		desired     time.Time
+		expires     bool
+	}{
+		{
+			name:    "default-retention",
+			desired: recycledAt.Add(defaultRetention),
+			expires: true,
+		},
+		{
+			name:        "policy-retention",
+			annotations: map[string]string{RetentionAnnotation: "168h0m0s"},
+			desired:     recycledAt.Add(time.Hour * 168),
+			expires:     true,
+		},
+		{
+			name:        "policy-retention-forever",
+			annotations: map[string]string{RetentionAnnotation: "0s"},
+		},
+		{
+			name:        "keep-annotation",
+			annotations: map[string]string{RetentionAnnotation: "1h", KeepAnnotation: "true"},
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			ri := &RecycleItem{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      map[string]string{RecycledAtLabel: "1700000000"},
+					Annotations: tt.annotations,
+				},
+			}
+
+			expireAt, expires, err := ri.ExpireAt(defaultRetention)
+			if err != nil {
+				t.Fatalf("✗ failed to get expiry: %v", err)
+			}
+			if expires != tt.expires {
+				t.Fatalf("✗ expected expires %v, got %v", tt.expires, expires)
+			}
+			if expires && !expireAt.Equal(tt.desired) {
+				t.Errorf("✗ expected %v, got %v", tt.desired, expireAt)
+			}
+		})
+	}
+}
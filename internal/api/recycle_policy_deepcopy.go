@@ -16,7 +16,11 @@ limitations under the License.
 
 package api
 
-import "k8s.io/apimachinery/pkg/runtime"
+import (
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
 
 func (in *RecyclePolicy) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
@@ -38,8 +42,79 @@ func (in *RecyclePolicy) DeepCopy() *RecyclePolicy {
 func (in *RecyclePolicy) DeepCopyInto(out *RecyclePolicy) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
-	out.ObjectMeta = in.ObjectMeta
-	out.Target = in.Target
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Target.DeepCopyInto(&out.Target)
+	if in.Retention != nil {
+		out.Retention = new(metav1.Duration)
+		*out.Retention = *in.Retention
+	}
+	if in.Webhook != nil {
+		out.Webhook = new(WebhookSettings)
+		in.Webhook.DeepCopyInto(out.Webhook)
+	}
+	if in.Redact != nil {
+		out.Redact = make([]RedactRule, len(in.Redact))
+		for i := range in.Redact {
+			in.Redact[i].DeepCopyInto(&out.Redact[i])
+		}
+	}
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+func (in *WebhookSettings) DeepCopyInto(out *WebhookSettings) {
+	*out = *in
+	if in.FailurePolicy != nil {
+		out.FailurePolicy = new(admissionregistrationv1.FailurePolicyType)
+		*out.FailurePolicy = *in.FailurePolicy
+	}
+	if in.TimeoutSeconds != nil {
+		out.TimeoutSeconds = new(int32)
+		*out.TimeoutSeconds = *in.TimeoutSeconds
+	}
+	if in.MatchPolicy != nil {
+		out.MatchPolicy = new(admissionregistrationv1.MatchPolicyType)
+		*out.MatchPolicy = *in.MatchPolicy
+	}
+}
+
+func (in *RedactRule) DeepCopyInto(out *RedactRule) {
+	*out = *in
+	if in.Fields != nil {
+		out.Fields = make([]string, len(in.Fields))
+		copy(out.Fields, in.Fields)
+	}
+}
+
+func (in *RecyclePolicyStatus) DeepCopyInto(out *RecyclePolicyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(in.Conditions))
+		for i := range in.Conditions {
+			in.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+	if in.Resources != nil {
+		out.Resources = make([]string, len(in.Resources))
+		copy(out.Resources, in.Resources)
+	}
+}
+
+func (in *RecycleTarget) DeepCopyInto(out *RecycleTarget) {
+	*out = *in
+	if in.Resources != nil {
+		out.Resources = make([]TargetResource, len(in.Resources))
+		copy(out.Resources, in.Resources)
+	}
+	if in.Namespaces != nil {
+		out.Namespaces = make([]string, len(in.Namespaces))
+		copy(out.Namespaces, in.Namespaces)
+	}
+	if in.ObjectSelector != nil {
+		out.ObjectSelector = in.ObjectSelector.DeepCopy()
+	}
+	if in.NamespaceSelector != nil {
+		out.NamespaceSelector = in.NamespaceSelector.DeepCopy()
+	}
 }
 
 func (in *RecyclePolicyList) DeepCopyObject() runtime.Object {
@@ -17,24 +17,183 @@ limitations under the License.
 package api
 
 import (
+	"fmt"
 	"slices"
+	"strings"
 
+	"github.com/ketches/kube-recycle-bin/pkg/util"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/util/rand"
 )
 
+const (
+	// TargetGroupResourceLabel records the group resource targeted by a
+	// RecyclePolicy.
+	TargetGroupResourceLabel = "krb.ketches.cn/target-gr"
+	// TargetNamespaceLabelPrefix followed by a namespace is set to "true" for
+	// every namespace targeted by a RecyclePolicy.
+	TargetNamespaceLabelPrefix = "krb.ketches.cn/target-namespace-"
+	// RecyclePolicyFinalizer keeps a RecyclePolicy until krb-controller has
+	// removed it from the webhook configuration.
+	RecyclePolicyFinalizer = "krb.ketches.cn/cleanup"
+)
+
+// DeletionPolicy decides what happens to the RecycleItems of a RecyclePolicy
+// when the policy is deleted.
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyKeep keeps the RecycleItems until they expire.
+	DeletionPolicyKeep DeletionPolicy = "Keep"
+	// DeletionPolicyPurge deletes the RecycleItems together with the policy.
+	DeletionPolicyPurge DeletionPolicy = "Purge"
+)
+
 type RecyclePolicy struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata"`
 
 	Target RecycleTarget `json:"target"`
+
+	// Retention is how long RecycleItems created by this policy are kept
+	// before they are garbage collected. A zero duration keeps them forever,
+	// and when unset the controller's default retention applies.
+	Retention *metav1.Duration `json:"retention,omitempty"`
+
+	// DeletionPolicy decides whether the RecycleItems created by this policy
+	// are kept or purged when the policy is deleted, defaults to Keep.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// Webhook overrides the krb-controller defaults of the webhook that
+	// intercepts deletions of the target.
+	Webhook *WebhookSettings `json:"webhook,omitempty"`
+
+	// Redact removes fields, such as credentials, from the objects recycled
+	// by this policy before they are stored.
+	Redact []RedactRule `json:"redact,omitempty"`
+
+	Status RecyclePolicyStatus `json:"status,omitempty"`
 }
 
 type RecycleTarget struct {
-	Group      string   `json:"group,omitempty"`
-	Resource   string   `json:"resource,omitempty"`
+	// Group and Resource are a target resource, a "*" resource targets all
+	// resources of the group.
+	Group    string `json:"group,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	// Resources are target resources in addition to Group and Resource.
+	Resources []TargetResource `json:"resources,omitempty"`
+	// Category targets all resources in the category, such as "all".
+	Category string `json:"category,omitempty"`
+
 	Namespaces []string `json:"namespaces,omitempty"`
+
+	// ObjectSelector limits the target to objects whose labels match.
+	ObjectSelector *metav1.LabelSelector `json:"objectSelector,omitempty"`
+	// NamespaceSelector limits the target to objects in namespaces whose
+	// labels match, in addition to the namespace list.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+// TargetResource is a resource targeted by a RecyclePolicy.
+type TargetResource struct {
+	Group string `json:"group,omitempty"`
+	// Resource is the plural resource name, "*" targets all resources of the
+	// group.
+	Resource string `json:"resource"`
+}
+
+func (tr TargetResource) GroupResource() schema.GroupResource {
+	return schema.GroupResource{Group: tr.Group, Resource: tr.Resource}
+}
+
+// IsWildcard reports whether the target resource is all resources of the
+// group.
+func (tr TargetResource) IsWildcard() bool {
+	return tr.Resource == "*"
+}
+
+const (
+	// RecyclePolicyConditionReady is true when the policy is recycling its
+	// target, which requires all other conditions to be true.
+	RecyclePolicyConditionReady = "Ready"
+	// RecyclePolicyConditionWebhookConfigured is true when the policy is in
+	// the webhook configuration.
+	RecyclePolicyConditionWebhookConfigured = "WebhookConfigured"
+	// RecyclePolicyConditionTargetResolved is true when the target resource
+	// is served by the cluster.
+	RecyclePolicyConditionTargetResolved = "TargetResolved"
+)
+
+// WebhookSettings of the webhook that intercepts deletions of the target,
+// unset fields fall back to the krb-controller defaults.
+type WebhookSettings struct {
+	// FailurePolicy is Fail to reject deletions while the webhook is
+	// unavailable, or Ignore to let them through unrecycled.
+	FailurePolicy *admissionregistrationv1.FailurePolicyType `json:"failurePolicy,omitempty"`
+	// TimeoutSeconds is how long the API server waits for the webhook, between 1 and 30.
+	TimeoutSeconds *int32 `json:"timeoutSeconds,omitempty"`
+	// MatchPolicy is Exact or Equivalent, the latter also intercepts
+	// deletions of the target through other API versions or groups.
+	MatchPolicy *admissionregistrationv1.MatchPolicyType `json:"matchPolicy,omitempty"`
+}
+
+// WithDefaults returns the settings with unset fields taken from defaults.
+func (s *WebhookSettings) WithDefaults(defaults WebhookSettings) WebhookSettings {
+	if s == nil {
+		return defaults
+	}
+	result := *s
+	if result.FailurePolicy == nil {
+		result.FailurePolicy = defaults.FailurePolicy
+	}
+	if result.TimeoutSeconds == nil {
+		result.TimeoutSeconds = defaults.TimeoutSeconds
+	}
+	if result.MatchPolicy == nil {
+		result.MatchPolicy = defaults.MatchPolicy
+	}
+	return result
+}
+
+// Validate checks the values of the set fields.
+func (s *WebhookSettings) Validate() error {
+	if s.FailurePolicy != nil && *s.FailurePolicy != admissionregistrationv1.Fail && *s.FailurePolicy != admissionregistrationv1.Ignore {
+		return fmt.Errorf("invalid failure policy %q, must be one of: Fail|Ignore", *s.FailurePolicy)
+	}
+	if s.TimeoutSeconds != nil && (*s.TimeoutSeconds < 1 || *s.TimeoutSeconds > 30) {
+		return fmt.Errorf("invalid timeout seconds %d, must be between 1 and 30", *s.TimeoutSeconds)
+	}
+	if s.MatchPolicy != nil && *s.MatchPolicy != admissionregistrationv1.Exact && *s.MatchPolicy != admissionregistrationv1.Equivalent {
+		return fmt.Errorf("invalid match policy %q, must be one of: Exact|Equivalent", *s.MatchPolicy)
+	}
+	return nil
+}
+
+type RecyclePolicyStatus struct {
+	// ObservedGeneration is the generation of the policy the status is for.
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	// Conditions are the Ready, WebhookConfigured and TargetResolved
+	// conditions of the policy.
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+	// WebhookConfiguration is the name of the ValidatingWebhookConfiguration
+	// the policy is in.
+	WebhookConfiguration string `json:"webhookConfiguration,omitempty"`
+	// RecycledCount is the number of objects recycled by the policy, those
+	// in the recycle bin and those restored or purged since. It is counted by
+	// krb-controller from the RecycleItems labelled with the policy.
+	RecycledCount int64 `json:"recycledCount,omitempty"`
+	// RestoredCount is the number of objects recycled by the policy and
+	// restored with krb-cli.
+	RestoredCount int64 `json:"restoredCount,omitempty"`
+	// PurgedCount is the number of objects recycled by the policy whose
+	// RecycleItems were deleted without being restored, such as expired ones.
+	PurgedCount int64 `json:"purgedCount,omitempty"`
+	// Resources are the group resources the target is resolved to, including
+	// those of "*" resources and the category.
+	Resources []string `json:"resources,omitempty"`
 }
 
 type RecyclePolicyList struct {
@@ -44,37 +203,184 @@ type RecyclePolicyList struct {
 }
 
 func NewRecyclePolicy(gvr schema.GroupVersionResource, targetNamespaces []string) *RecyclePolicy {
-	targetNamespaces = slices.DeleteFunc(targetNamespaces, func(ns string) bool {
-		return ns == metav1.NamespaceAll
-	})
+	return NewRecyclePolicyFor(RecycleTarget{
+		Group:    gvr.Group,
+		Resource: gvr.Resource,
+	}, targetNamespaces)
+}
 
-	labels := map[string]string{
-		"krb.ketches.cn/target-gr": gvr.GroupResource().String(),
-	}
-	for _, ns := range targetNamespaces {
-		labels["krb.ketches.cn/target-namespace-"+ns] = "true"
+// NewRecyclePolicyFor returns a RecyclePolicy for the target, named after its
+// resource, the group of its "*" resource or its category. Only policies for
+// a single resource are labelled with the target group resource.
+func NewRecyclePolicyFor(target RecycleTarget, targetNamespaces []string) *RecyclePolicy {
+	var name string
+	switch {
+	case target.Category != "":
+		name = "recycle-category-" + target.Category
+	case target.Resource == "*":
+		name = "recycle-all-" + util.If(target.Group == "", "core", strings.ReplaceAll(target.Group, ".", "-"))
+	default:
+		name = "recycle-" + target.Resource
 	}
 
-	return &RecyclePolicy{
+	policy := &RecyclePolicy{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: GroupVersion.String(),
 			Kind:       RecyclePolicyKind,
 		},
 		ObjectMeta: metav1.ObjectMeta{
-			Name:   "recycle-" + gvr.Resource + "-" + rand.String(8),
-			Labels: labels,
-		},
-		Target: RecycleTarget{
-			Group:      gvr.Group,
-			Resource:   gvr.Resource,
-			Namespaces: targetNamespaces,
+			Name: name + "-" + rand.String(8),
 		},
+		Target: target,
+	}
+	if resources := target.TargetResources(); target.Category == "" && len(resources) == 1 && !resources[0].IsWildcard() {
+		policy.Labels = map[string]string{
+			TargetGroupResourceLabel: resources[0].GroupResource().String(),
+		}
 	}
+	policy.SetTargetNamespaces(targetNamespaces)
+	return policy
 }
 
-func (rt *RecycleTarget) GroupResource() schema.GroupResource {
-	return schema.GroupResource{
-		Group:    rt.Group,
-		Resource: rt.Resource,
+// SetTargetNamespaces replaces the target namespaces of the policy and keeps
+// the target namespace labels in sync. No namespaces target all namespaces.
+func (p *RecyclePolicy) SetTargetNamespaces(namespaces []string) {
+	namespaces = slices.DeleteFunc(slices.Clone(namespaces), func(ns string) bool {
+		return ns == metav1.NamespaceAll
+	})
+	slices.Sort(namespaces)
+	namespaces = slices.Compact(namespaces)
+
+	for k := range p.Labels {
+		if strings.HasPrefix(k, TargetNamespaceLabelPrefix) {
+			delete(p.Labels, k)
+		}
+	}
+	if len(namespaces) > 0 && p.Labels == nil {
+		p.Labels = map[string]string{}
+	}
+	for _, ns := range namespaces {
+		p.Labels[TargetNamespaceLabelPrefix+ns] = "true"
+	}
+	p.Target.Namespaces = namespaces
+}
+
+// Matches reports whether the policy targets the object of the given group
+// resource in the given categories and namespace, with the given object
+// labels and labels of its namespace. Namespace labels are ignored for
+// cluster-scoped objects, which only match policies limited to namespaces if
+// their resource is listed explicitly.
+func (p *RecyclePolicy) Matches(gr schema.GroupResource, categories []string, namespace string, objectLabels, namespaceLabels labels.Set) bool {
+	if !p.Target.MatchesResource(gr, categories) {
+		return false
+	}
+	if !selectorMatches(p.Target.ObjectSelector, objectLabels) {
+		return false
+	}
+	if namespace == "" {
+		return !p.Target.LimitedToNamespaces() || slices.ContainsFunc(p.Target.TargetResources(), func(tr TargetResource) bool {
+			return tr.GroupResource() == gr
+		})
+	}
+	if !selectorMatches(p.Target.NamespaceSelector, namespaceLabels) {
+		return false
+	}
+	if len(p.Target.Namespaces) == 0 {
+		return true
+	}
+	return slices.ContainsFunc(p.Target.Namespaces, func(ns string) bool {
+		return ns == namespace || ns == metav1.NamespaceAll || ns == "*"
+	})
+}
+
+// selectorMatches reports whether the label selector matches the labels, an
+// unset selector matches everything.
+func selectorMatches(labelSelector *metav1.LabelSelector, set labels.Set) bool {
+	if labelSelector == nil {
+		return true
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return false
+	}
+	return selector.Matches(set)
+}
+
+// TargetResources returns Group and Resource together with Resources.
+func (rt *RecycleTarget) TargetResources() []TargetResource {
+	var result []TargetResource
+	if rt.Resource != "" {
+		result = append(result, TargetResource{Group: rt.Group, Resource: rt.Resource})
+	}
+	return append(result, rt.Resources...)
+}
+
+// IsWildcard reports whether the target is resolved through discovery,
+// because it has a "*" resource or a category.
+func (rt *RecycleTarget) IsWildcard() bool {
+	return rt.Category != "" || slices.ContainsFunc(rt.TargetResources(), TargetResource.IsWildcard)
+}
+
+// LimitedToNamespaces reports whether the target lists namespaces or selects
+// them by labels.
+func (rt *RecycleTarget) LimitedToNamespaces() bool {
+	return rt.NamespaceSelector != nil || slices.ContainsFunc(rt.Namespaces, func(ns string) bool {
+		return ns != metav1.NamespaceAll && ns != "*"
+	})
+}
+
+// MatchesResource reports whether the target covers the group resource in
+// the given categories. Subresources never match.
+func (rt *RecycleTarget) MatchesResource(gr schema.GroupResource, categories []string) bool {
+	if strings.Contains(gr.Resource, "/") {
+		return false
+	}
+	if rt.Category != "" && slices.Contains(categories, rt.Category) {
+		return true
+	}
+	return slices.ContainsFunc(rt.TargetResources(), func(tr TargetResource) bool {
+		return tr.Group == gr.Group && (tr.IsWildcard() || tr.Resource == gr.Resource)
+	})
+}
+
+// Resolve returns the sorted group resources of the target. Resources listed
+// explicitly are always returned, while "*" resources and the category are
+// resolved to the discovered resources that can be deleted, leaving out
+// excluded resources, and cluster-scoped resources if the target is limited
+// to namespaces. The discovered resources must have their group set.
+func (rt *RecycleTarget) Resolve(discovered []metav1.APIResource, exclusions *Exclusions) []schema.GroupResource {
+	var result []schema.GroupResource
+	for _, tr := range rt.TargetResources() {
+		if !tr.IsWildcard() {
+			result = append(result, tr.GroupResource())
+		}
+	}
+	if rt.IsWildcard() {
+		for _, resource := range discovered {
+			gr := schema.GroupResource{Group: resource.Group, Resource: resource.Name}
+			if !slices.Contains(resource.Verbs, "delete") || !rt.MatchesResource(gr, resource.Categories) ||
+				exclusions.ExcludesResource(gr) || (!resource.Namespaced && rt.LimitedToNamespaces()) {
+				continue
+			}
+			result = append(result, gr)
+		}
+	}
+
+	slices.SortFunc(result, func(a, b schema.GroupResource) int {
+		return strings.Compare(a.String(), b.String())
+	})
+	return slices.Compact(result)
+}
+
+// String returns the target resources and category, such as
+// "deployments.apps,*.batch,category:all".
+func (rt *RecycleTarget) String() string {
+	var result []string
+	for _, tr := range rt.TargetResources() {
+		result = append(result, tr.GroupResource().String())
+	}
+	if rt.Category != "" {
+		result = append(result, "category:"+rt.Category)
 	}
+	return strings.Join(result, ",")
 }
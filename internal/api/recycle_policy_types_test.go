@@ -0,0 +1,137 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRecyclePolicySetTargetNamespaces(t *testing.T) {
+	policy := NewRecyclePolicy(schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"}, []string{"prod", "dev"})
+	policy.SetTargetNamespaces([]string{"dev", "staging", "dev"})
+
+	if desired := []string{"dev", "staging"}; !slices.Equal(policy.Target.Namespaces, desired) {
+		t.Errorf("✗ expected namespaces %v, got %v", desired, policy.Target.Namespaces)
+	}
+	desired := map[string]string{
+		TargetGroupResourceLabel:               "deployments.apps",
+		TargetNamespaceLabelPrefix + "dev":     "true",
+		TargetNamespaceLabelPrefix + "staging": "true",
+	}
+	if !maps.Equal(policy.Labels, desired) {
+		t.Errorf("✗ expected labels %v, got %v", desired, policy.Labels)
+	}
+}
+
+func TestRecycleTargetResolve(t *testing.T) {
+	discovered := []metav1.APIResource{
+		{Name: "pods", Namespaced: true, Verbs: []string{"delete"}, Categories: []string{"all"}},
+		{Name: "pods/log", Namespaced: true, Verbs: []string{"get"}},
+		{Name: "events", Namespaced: true, Verbs: []string{"delete"}},
+		{Name: "bindings", Namespaced: true, Verbs: []string{"create"}},
+		{Name: "nodes", Verbs: []string{"delete"}},
+		{Group: "apps", Name: "deployments", Namespaced: true, Verbs: []string{"delete"}, Categories: []string{"all"}},
+		{Group: "apps", Name: "deployments/scale", Namespaced: true, Verbs: []string{"update"}},
+		{Group: "apps", Name: "statefulsets", Namespaced: true, Verbs: []string{"delete"}, Categories: []string{"all"}},
+	}
+	exclusions := NewExclusions([]string{"events"}, nil)
+
+	testdata := []struct {
+		name    string
+		target  RecycleTarget
+		desired []string
+	}{
+		{
+			name:    "single",
+			target:  RecycleTarget{Group: "apps", Resource: "deployments"},
+			desired: []string{"deployments.apps"},
+		},
+		{
+			name:    "list-with-unserved-resource",
+			target:  RecycleTarget{Resource: "configmaps", Resources: []TargetResource{{Group: "example.com", Resource: "foos"}}},
+			desired: []string{"configmaps", "foos.example.com"},
+		},
+		{
+			name:    "all-in-group",
+			target:  RecycleTarget{Group: "apps", Resource: "*"},
+			desired: []string{"deployments.apps", "statefulsets.apps"},
+		},
+		{
+			name:    "all-in-core-group",
+			target:  RecycleTarget{Resource: "*"},
+			desired: []string{"nodes", "pods"},
+		},
+		{
+			name:    "all-in-core-group-limited-to-namespaces",
+			target:  RecycleTarget{Resource: "*", Namespaces: []string{"dev"}},
+			desired: []string{"pods"},
+		},
+		{
+			name:    "category",
+			target:  RecycleTarget{Category: "all", Resources: []TargetResource{{Group: "apps", Resource: "deployments"}}},
+			desired: []string{"deployments.apps", "pods", "statefulsets.apps"},
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, gr := range tt.target.Resolve(discovered, &exclusions) {
+				got = append(got, gr.String())
+			}
+			if !slices.Equal(got, tt.desired) {
+				t.Errorf("✗ expected %v, got %v", tt.desired, got)
+			}
+		})
+	}
+}
+
+func TestRecyclePolicyMatches(t *testing.T) {
+	policy := NewRecyclePolicyFor(RecycleTarget{Resource: "*", Category: "all"}, []string{"dev"})
+
+	testdata := []struct {
+		name       string
+		gr         schema.GroupResource
+		categories []string
+		namespace  string
+		desired    bool
+	}{
+		{name: "core-group", gr: schema.GroupResource{Resource: "configmaps"}, namespace: "dev", desired: true},
+		{name: "category", gr: schema.GroupResource{Group: "apps", Resource: "deployments"}, categories: []string{"all"}, namespace: "dev", desired: true},
+		{name: "other-group", gr: schema.GroupResource{Group: "apps", Resource: "deployments"}, namespace: "dev"},
+		{name: "other-namespace", gr: schema.GroupResource{Resource: "configmaps"}, namespace: "prod"},
+		{name: "subresource", gr: schema.GroupResource{Resource: "pods/log"}, namespace: "dev"},
+		{name: "cluster-scoped", gr: schema.GroupResource{Resource: "nodes"}},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.Matches(tt.gr, tt.categories, tt.namespace, nil, nil); got != tt.desired {
+				t.Errorf("✗ expected %v, got %v", tt.desired, got)
+			}
+		})
+	}
+
+	if _, ok := policy.Labels[TargetGroupResourceLabel]; ok {
+		t.Errorf("✗ expected no target group resource label for wildcard policies, got %v", policy.Labels)
+	}
+}
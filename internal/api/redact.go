@@ -0,0 +1,820 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+// RedactRule removes fields from recycled objects of a group kind before
+// they are stored, so their values are never persisted.
+type RedactRule struct {
+	// Group of the recycled objects, empty for the core group.
+	Group string `json:"group,omitempty"`
+	// Kind of the recycled objects, empty for all kinds.
+	Kind string `json:"kind,omitempty"`
+	// Fields are dot-separated paths of the fields to redact as in sanitize
+	// rules, such as "metadata.annotations.example.com/token", or JSONPath
+	// expressions as in kubectl, such as
+	// "$.spec.template.spec.containers[*].env[?(@.name=='API_TOKEN')].value".
+	Fields []string `json:"fields"`
+	// ValuePattern limits the rule to string values matching the regular
+	// expression, such as "^ghp_[A-Za-z0-9]{36}$".
+	ValuePattern string `json:"valuePattern,omitempty"`
+}
+
+// RedactedField is a field removed from the recycled object by a redact
+// rule.
+type RedactedField struct {
+	// Path is the JSONPath of the field in the object, such as
+	// $.spec.containers[0].env[1].value, dots in keys are escaped as in
+	// $.metadata.annotations.example\.com/token.
+	Path string `json:"path"`
+	// Type of the removed value. One of: string|number|boolean|object|array|null
+	Type string `json:"type"`
+}
+
+func (r RedactRule) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: r.Group, Kind: r.Kind}
+}
+
+// appliesTo reports whether the rule applies to objects of the group kind.
+func (r RedactRule) appliesTo(gk schema.GroupKind) bool {
+	return r.Group == gk.Group && (r.Kind == "" || r.Kind == gk.Kind)
+}
+
+// Validate rejects rules without fields, with invalid field paths, field
+// paths matching the object itself or the fields identifying it, or an
+// invalid value pattern.
+func (r RedactRule) Validate() error {
+	if len(r.Fields) == 0 {
+		return fmt.Errorf("redact rule for %s has no fields", r.GroupKind().String())
+	}
+	probe := map[string]any{}
+	for _, path := range identityFields {
+		_ = path.set(probe, "")
+	}
+	for _, field := range r.Fields {
+		match, err := parseFieldPath(field)
+		if err != nil {
+			return err
+		}
+		paths, err := match(probe)
+		if err != nil {
+			return fmt.Errorf("invalid field path %q: %w", field, err)
+		}
+		for _, path := range paths {
+			if err := checkRedactable(path); err != nil {
+				return fmt.Errorf("invalid field path %q: %w", field, err)
+			}
+		}
+	}
+	if _, err := regexp.Compile(r.ValuePattern); err != nil {
+		return fmt.Errorf("invalid value pattern %q: %w", r.ValuePattern, err)
+	}
+	return nil
+}
+
+// Redact removes the fields matched by the rules applying to the recycled
+// object from its plain JSON, recording them in Redacted. The last applied
+// configuration is removed as well if any field is redacted, as it may hold
+// the same values.
+func (obj *RecycledObject) Redact(rules []RedactRule) error {
+	var applied []RedactRule
+	for _, rule := range rules {
+		if rule.appliesTo(obj.ObjectGroupKind()) {
+			applied = append(applied, rule)
+		}
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+
+	data, err := obj.Data()
+	if err != nil {
+		return err
+	}
+	var content map[string]any
+	if err := json.Unmarshal(data, &content); err != nil {
+		return err
+	}
+
+	var matched []fieldPath
+	for _, rule := range applied {
+		pattern, err := regexp.Compile(rule.ValuePattern)
+		if err != nil {
+			return fmt.Errorf("invalid value pattern %q: %w", rule.ValuePattern, err)
+		}
+		for _, field := range rule.Fields {
+			segments, err := parseFieldPath(field)
+			if err != nil {
+				return err
+			}
+			paths, err := segments(content)
+			if err != nil {
+				return fmt.Errorf("failed to match field path %q: %w", field, err)
+			}
+			for _, path := range paths {
+				if err := checkRedactable(path); err != nil {
+					return fmt.Errorf("invalid field path %q: %w", field, err)
+				}
+				if value, _ := path.get(content); rule.ValuePattern == "" || isMatchingString(pattern, value) {
+					matched = append(matched, path)
+				}
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	lastApplied := fieldPath{"metadata", "annotations", corev1.LastAppliedConfigAnnotation}
+	if _, ok := lastApplied.get(content); ok {
+		matched = append(matched, lastApplied)
+	}
+
+	// removing a field removes the fields below it, and list items are
+	// removed from the last one so the indices of the others stay valid
+	slices.SortFunc(matched, compareFieldPaths)
+	matched = slices.CompactFunc(matched, func(a, b fieldPath) bool { return b.within(a) })
+	for _, path := range matched {
+		value, _ := path.get(content)
+		obj.Redacted = append(obj.Redacted, RedactedField{Path: path.String(), Type: jsonType(value)})
+	}
+	for _, path := range slices.Backward(matched) {
+		path.remove(content)
+	}
+
+	data, err = json.Marshal(content)
+	if err != nil {
+		return err
+	}
+	return obj.SetData(data, obj.Encoding)
+}
+
+// identityFields identify a recycled object, so they are never redacted.
+var identityFields = []fieldPath{{"apiVersion"}, {"kind"}, {"metadata", "name"}, {"metadata", "namespace"}}
+
+// checkRedactable rejects the path if removing it removes the object itself
+// or a field identifying it.
+func checkRedactable(path fieldPath) error {
+	for _, field := range identityFields {
+		if field.within(path) {
+			return fmt.Errorf("%s identifies the object and can't be redacted", path.String())
+		}
+	}
+	return nil
+}
+
+// SetRedactedField sets the value of the redacted field in the object, parsed
+// according to the type of the removed value. List items are inserted at
+// their index, so fields have to be set in the order they were redacted.
+func SetRedactedField(content map[string]any, field RedactedField, value string) error {
+	path, err := parseConcretePath(field.Path)
+	if err != nil {
+		return err
+	}
+
+	var parsed any
+	switch field.Type {
+	case "string":
+		parsed = value
+	case "boolean":
+		if parsed, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("invalid boolean value %q for %s", value, field.Path)
+		}
+	case "number":
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			parsed = i
+		} else if parsed, err = strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("invalid number value %q for %s", value, field.Path)
+		}
+	default:
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return fmt.Errorf("invalid %s value %q for %s: %w", field.Type, value, field.Path, err)
+		}
+	}
+	return path.set(content, parsed)
+}
+
+// After returns the field with its path in the object after the skipped
+// fields were left unset, list items after a skipped list item move up.
+func (f RedactedField) After(skipped []RedactedField) RedactedField {
+	path, err := parseConcretePath(f.Path)
+	if err != nil {
+		return f
+	}
+	shifted := slices.Clone(path)
+	for _, s := range skipped {
+		skippedPath, err := parseConcretePath(s.Path)
+		if err != nil || len(skippedPath) == 0 || len(skippedPath) > len(path) {
+			continue
+		}
+		n := len(skippedPath) - 1
+		index, isIndex := skippedPath[n].(int)
+		if !isIndex || compareFieldPaths(path[:n], skippedPath[:n]) != 0 {
+			continue
+		}
+		if i, ok := path[n].(int); ok && i > index {
+			shifted[n] = shifted[n].(int) - 1
+		}
+	}
+	f.Path = shifted.String()
+	return f
+}
+
+// parseConcretePath parses the JSONPath of a redacted field, which only has
+// keys and indices.
+func parseConcretePath(s string) (fieldPath, error) {
+	nodes, err := parseJSONPath(s)
+	if err != nil {
+		return nil, err
+	}
+	path := make(fieldPath, 0, len(nodes))
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *jsonpath.FieldNode:
+			if n.Value != "" {
+				path = append(path, n.Value)
+			}
+		case *jsonpath.ArrayNode:
+			if !n.Params[0].Known || n.Params[0].Value < 0 || !n.Params[1].Derived || n.Params[2].Known {
+				return nil, fmt.Errorf("redacted field %s is not a concrete path", s)
+			}
+			path = append(path, n.Params[0].Value)
+		default:
+			return nil, fmt.Errorf("redacted field %s is not a concrete path", s)
+		}
+	}
+	return path, nil
+}
+
+func isMatchingString(pattern *regexp.Regexp, value any) bool {
+	s, ok := value.(string)
+	return ok && pattern.MatchString(s)
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64, int64, json.Number:
+		return "number"
+	case map[string]any:
+		return "object"
+	case []any:
+		return "array"
+	}
+	return "null"
+}
+
+// fieldMatcher returns the concrete paths of the fields in obj matched by a
+// field path.
+type fieldMatcher func(obj any) ([]fieldPath, error)
+
+// parseFieldPath parses a dot-separated field path or a JSONPath expression.
+func parseFieldPath(s string) (fieldMatcher, error) {
+	if s == "" {
+		return nil, fmt.Errorf("empty field path")
+	}
+	if !strings.HasPrefix(s, "$") && !strings.HasPrefix(s, "{") {
+		parts := strings.Split(s, ".")
+		if slices.Contains(parts, "") {
+			return nil, fmt.Errorf("invalid field path %q", s)
+		}
+		return func(obj any) ([]fieldPath, error) {
+			return matchDottedPath(obj, parts, nil), nil
+		}, nil
+	}
+
+	nodes, err := parseJSONPath(s)
+	if err != nil {
+		return nil, err
+	}
+	return func(obj any) ([]fieldPath, error) {
+		return matchJSONPath(obj, nodes, nil)
+	}, nil
+}
+
+// parseJSONPath parses a JSONPath expression with the parser of kubectl,
+// with or without the enclosing braces.
+func parseJSONPath(s string) ([]jsonpath.Node, error) {
+	expr := s
+	if !strings.HasPrefix(expr, "{") {
+		expr = "{" + expr + "}"
+	}
+	parser, err := jsonpath.Parse("field", expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field path %q: %w", s, err)
+	}
+	if len(parser.Root.Nodes) != 1 {
+		return nil, fmt.Errorf("invalid field path %q: expected a single expression", s)
+	}
+	action, ok := parser.Root.Nodes[0].(*jsonpath.ListNode)
+	if !ok || len(action.Nodes) == 0 {
+		return nil, fmt.Errorf("invalid field path %q", s)
+	}
+	if err := validateJSONPath(action.Nodes); err != nil {
+		return nil, fmt.Errorf("invalid field path %q: %w", s, err)
+	}
+	return action.Nodes, nil
+}
+
+// filterOperators are the operators supported in filters, exists for filters
+// without an operator such as [?(@.valueFrom)].
+var filterOperators = []string{"exists", "==", "!=", "<", ">", "<=", ">="}
+
+// validateJSONPath rejects nodes that don't select fields, such as
+// identifiers and literals outside of filters.
+func validateJSONPath(nodes []jsonpath.Node) error {
+	for _, node := range nodes {
+		switch n := node.(type) {
+		case *jsonpath.FieldNode, *jsonpath.WildcardNode, *jsonpath.RecursiveNode:
+		case *jsonpath.ArrayNode:
+			if n.Params[2].Known && n.Params[2].Value <= 0 {
+				return fmt.Errorf("step must be positive")
+			}
+		case *jsonpath.ListNode:
+			if err := validateJSONPath(n.Nodes); err != nil {
+				return err
+			}
+		case *jsonpath.UnionNode:
+			for _, list := range n.Nodes {
+				if err := validateJSONPath(list.Nodes); err != nil {
+					return err
+				}
+			}
+		case *jsonpath.FilterNode:
+			if !slices.Contains(filterOperators, n.Operator) {
+				return fmt.Errorf("unsupported filter operator %q", n.Operator)
+			}
+			for _, operand := range []*jsonpath.ListNode{n.Left, n.Right} {
+				if literal(operand) == nil {
+					if err := validateJSONPath(operand.Nodes); err != nil {
+						return err
+					}
+				}
+			}
+		default:
+			return fmt.Errorf("unsupported %s", node.Type())
+		}
+	}
+	return nil
+}
+
+// matchDottedPath returns the concrete paths of the fields in obj matched by
+// the parts of a dot-separated path. The remaining parts are matched as a
+// single key containing dots first, such as an annotation key, and a "*"
+// part matches every item of a list or every value of a map.
+func matchDottedPath(obj any, parts []string, prefix fieldPath) []fieldPath {
+	if len(parts) == 0 {
+		return []fieldPath{slices.Clone(prefix)}
+	}
+
+	var result []fieldPath
+	switch o := obj.(type) {
+	case map[string]any:
+		if key := strings.Join(parts, "."); len(parts) > 1 {
+			if _, ok := o[key]; ok {
+				return []fieldPath{append(slices.Clone(prefix), key)}
+			}
+		}
+		if parts[0] == "*" {
+			for _, key := range sortedKeys(o) {
+				result = append(result, matchDottedPath(o[key], parts[1:], append(prefix, key))...)
+			}
+		} else if v, ok := o[parts[0]]; ok {
+			result = matchDottedPath(v, parts[1:], append(prefix, parts[0]))
+		}
+	case []any:
+		if parts[0] == "*" {
+			for i, item := range o {
+				result = append(result, matchDottedPath(item, parts[1:], append(prefix, i))...)
+			}
+		}
+	}
+	return result
+}
+
+// matchJSONPath returns the concrete paths of the fields in obj matched by
+// the nodes of a parsed JSONPath expression.
+func matchJSONPath(obj any, nodes []jsonpath.Node, prefix fieldPath) ([]fieldPath, error) {
+	if len(nodes) == 0 {
+		return []fieldPath{slices.Clone(prefix)}, nil
+	}
+
+	rest := nodes[1:]
+	var result []fieldPath
+	next := func(v any, nodes []jsonpath.Node, path fieldPath) error {
+		matched, err := matchJSONPath(v, nodes, path)
+		result = append(result, matched...)
+		return err
+	}
+	var err error
+	switch node := nodes[0].(type) {
+	case *jsonpath.ListNode:
+		err = next(obj, append(slices.Clone(node.Nodes), rest...), prefix)
+	case *jsonpath.FieldNode:
+		if node.Value == "" {
+			err = next(obj, rest, prefix)
+		} else if o, ok := obj.(map[string]any); ok {
+			if v, ok := o[node.Value]; ok {
+				err = next(v, rest, append(prefix, node.Value))
+			}
+		}
+	case *jsonpath.WildcardNode:
+		err = eachChild(obj, func(key, v any) error {
+			return next(v, rest, append(prefix, key))
+		})
+	case *jsonpath.RecursiveNode:
+		// the value itself and every value below it
+		if err = next(obj, rest, prefix); err == nil {
+			err = eachChild(obj, func(key, v any) error {
+				return next(v, nodes, append(prefix, key))
+			})
+		}
+	case *jsonpath.ArrayNode:
+		if o, ok := obj.([]any); ok {
+			for _, i := range arrayIndices(node.Params, len(o)) {
+				if err = next(o[i], rest, append(prefix, i)); err != nil {
+					break
+				}
+			}
+		}
+	case *jsonpath.FilterNode:
+		if o, ok := obj.([]any); ok {
+			for i, item := range o {
+				var matches bool
+				if matches, err = filterMatches(item, node); err == nil && matches {
+					err = next(item, rest, append(prefix, i))
+				}
+				if err != nil {
+					break
+				}
+			}
+		}
+	case *jsonpath.UnionNode:
+		for _, list := range node.Nodes {
+			if err = next(obj, append([]jsonpath.Node{list}, rest...), prefix); err != nil {
+				break
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported %s", node.Type())
+	}
+	return result, err
+}
+
+// eachChild calls f with the key and value of every value of a map in the
+// order of the keys, or the index and value of every item of a list.
+func eachChild(obj any, f func(key, v any) error) error {
+	switch o := obj.(type) {
+	case map[string]any:
+		for _, key := range sortedKeys(o) {
+			if err := f(key, o[key]); err != nil {
+				return err
+			}
+		}
+	case []any:
+		for i, item := range o {
+			if err := f(i, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// arrayIndices returns the indices of a list of length n selected by the
+// start, end and step of an index or slice, negative ones count from the end.
+func arrayIndices(params [3]jsonpath.ParamsEntry, n int) []int {
+	start, end, step := 0, n, 1
+	if params[0].Known {
+		start = params[0].Value
+	}
+	if params[1].Known {
+		end = params[1].Value
+		// the end of [-1] is derived as 0
+		if end < 0 || (end == 0 && params[1].Derived) {
+			end += n
+		}
+	}
+	if start < 0 {
+		start += n
+	}
+	if params[2].Known {
+		step = params[2].Value
+	}
+
+	var result []int
+	for i := max(start, 0); i < min(end, n); i += step {
+		result = append(result, i)
+	}
+	return result
+}
+
+// filterMatches reports whether the list item matches the filter, which is
+// true if any value of the left operand compares to any value of the right
+// one.
+func filterMatches(item any, filter *jsonpath.FilterNode) (bool, error) {
+	left, err := filterOperand(item, filter.Left)
+	if err != nil || filter.Operator == "exists" {
+		return len(left) > 0, err
+	}
+	right, err := filterOperand(item, filter.Right)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range left {
+		for _, r := range right {
+			if compareValues(l, r, filter.Operator) {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// filterOperand returns the literal of the operand or the values it matches
+// in the list item.
+func filterOperand(item any, operand *jsonpath.ListNode) ([]any, error) {
+	if value := literal(operand); value != nil {
+		return []any{value}, nil
+	}
+	paths, err := matchJSONPath(item, operand.Nodes, nil)
+	if err != nil {
+		return nil, err
+	}
+	values := make([]any, 0, len(paths))
+	for _, path := range paths {
+		value, _ := path.get(item)
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// literal returns the string, number or boolean of an operand that is a
+// literal, numbers as float64 like those of decoded JSON, and nil otherwise.
+func literal(operand *jsonpath.ListNode) any {
+	if len(operand.Nodes) != 1 {
+		return nil
+	}
+	switch n := operand.Nodes[0].(type) {
+	case *jsonpath.TextNode:
+		return n.Text
+	case *jsonpath.IntNode:
+		return float64(n.Value)
+	case *jsonpath.FloatNode:
+		return n.Value
+	case *jsonpath.BoolNode:
+		return n.Value
+	}
+	return nil
+}
+
+// compareValues compares strings, numbers and booleans of the same type with
+// the operator of a filter, booleans are only equal or not.
+func compareValues(a, b any, operator string) bool {
+	var c int
+	switch x := a.(type) {
+	case string:
+		y, ok := b.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(x, y)
+	case float64:
+		y, ok := b.(float64)
+		if !ok {
+			return false
+		}
+		c = cmp.Compare(x, y)
+	case bool:
+		y, ok := b.(bool)
+		if !ok || (operator != "==" && operator != "!=") {
+			return false
+		}
+		if x != y {
+			c = 1
+		}
+	default:
+		return false
+	}
+
+	switch operator {
+	case "==":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case ">":
+		return c > 0
+	case "<=":
+		return c <= 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// fieldPath is a concrete path in an object, made of map keys and list
+// indices.
+type fieldPath []any
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// get returns the value at the path in obj.
+func (p fieldPath) get(obj any) (any, bool) {
+	for _, segment := range p {
+		switch o := obj.(type) {
+		case map[string]any:
+			key, ok := segment.(string)
+			if !ok {
+				return nil, false
+			}
+			if obj, ok = o[key]; !ok {
+				return nil, false
+			}
+		case []any:
+			index, ok := segment.(int)
+			if !ok || index >= len(o) {
+				return nil, false
+			}
+			obj = o[index]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// remove removes the field at the path from obj.
+func (p fieldPath) remove(obj map[string]any) {
+	if len(p) == 0 {
+		return
+	}
+	parent, ok := p[:len(p)-1].get(obj)
+	if !ok {
+		return
+	}
+	switch o := parent.(type) {
+	case map[string]any:
+		if key, ok := p[len(p)-1].(string); ok {
+			delete(o, key)
+		}
+	case []any:
+		if index, ok := p[len(p)-1].(int); ok && index < len(o) {
+			p[:len(p)-1].setIn(obj, slices.Delete(o, index, index+1))
+		}
+	}
+}
+
+// set sets the value at the path in obj, creating missing maps and inserting
+// list items at their index.
+func (p fieldPath) set(obj map[string]any, value any) error {
+	if len(p) == 0 {
+		return fmt.Errorf("can't replace the object")
+	}
+	var current any = obj
+	for i, segment := range p {
+		last := i == len(p)-1
+		switch o := current.(type) {
+		case map[string]any:
+			key, ok := segment.(string)
+			if !ok {
+				return fmt.Errorf("%s: expected a key, got index %v", p.String(), segment)
+			}
+			if last {
+				o[key] = value
+				return nil
+			}
+			if _, ok := o[key]; !ok {
+				if _, isIndex := p[i+1].(int); isIndex {
+					o[key] = []any{}
+				} else {
+					o[key] = map[string]any{}
+				}
+			}
+			current = o[key]
+		case []any:
+			index, ok := segment.(int)
+			if !ok || index > len(o) || (!last && index == len(o)) {
+				return fmt.Errorf("%s: index %v out of range", p.String(), segment)
+			}
+			if last {
+				return p[:i].setIn(obj, slices.Insert(o, index, value))
+			}
+			current = o[index]
+		default:
+			return fmt.Errorf("%s: parent is not an object or a list", p.String())
+		}
+	}
+	return nil
+}
+
+// setIn replaces the value at the path in obj.
+func (p fieldPath) setIn(obj map[string]any, value any) error {
+	if len(p) == 0 {
+		return fmt.Errorf("can't replace the object")
+	}
+	parent, ok := p[:len(p)-1].get(obj)
+	if !ok {
+		return fmt.Errorf("%s: parent not found", p.String())
+	}
+	switch o := parent.(type) {
+	case map[string]any:
+		o[p[len(p)-1].(string)] = value
+	case []any:
+		o[p[len(p)-1].(int)] = value
+	}
+	return nil
+}
+
+// within reports whether the path is the other path or below it.
+func (p fieldPath) within(other fieldPath) bool {
+	return len(p) >= len(other) && compareFieldPaths(p[:len(other)], other) == 0
+}
+
+// String formats the path as a JSONPath expression, escaping the characters
+// of keys that would end a field, such as the dots of annotation keys.
+func (p fieldPath) String() string {
+	var b strings.Builder
+	b.WriteString("$")
+	for _, segment := range p {
+		switch s := segment.(type) {
+		case int:
+			fmt.Fprintf(&b, "[%d]", s)
+		case string:
+			b.WriteString(".")
+			for _, r := range s {
+				if strings.ContainsRune(escapedKeyChars, r) {
+					b.WriteByte('\\')
+				}
+				b.WriteRune(r)
+			}
+		}
+	}
+	return b.String()
+}
+
+// escapedKeyChars end a field in a JSONPath expression or make it a wildcard,
+// unless they are escaped.
+const escapedKeyChars = ".,[]$@{}* \t\r\n"
+
+// compareFieldPaths orders paths by their keys and the numeric order of their
+// indices, a path comes before the paths below it.
+func compareFieldPaths(a, b fieldPath) int {
+	for i := range min(len(a), len(b)) {
+		switch x := a[i].(type) {
+		case int:
+			y, ok := b[i].(int)
+			if !ok {
+				return -1
+			}
+			if x != y {
+				return x - y
+			}
+		case string:
+			y, ok := b[i].(string)
+			if !ok {
+				return 1
+			}
+			if c := strings.Compare(x, y); c != 0 {
+				return c
+			}
+		}
+	}
+	return len(a) - len(b)
+}
@@ -0,0 +1,335 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package api
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"sigs.k8s.io/yaml"
+)
+
+const redactTestData = `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    example.com/token: secret
+    kubectl.kubernetes.io/last-applied-configuration: '{"token":"secret"}'
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - {name: LOG_LEVEL, value: debug}
+        - {name: API_TOKEN, value: ghp_0123456789}
+        - {name: DB_PASSWORD, value: hunter2}
+      - name: sidecar
+        env:
+        - {name: API_TOKEN, value: ghp_9876543210}
+`
+
+func TestRecycledObjectRedact(t *testing.T) {
+	testdata := []struct {
+		name     string
+		rules    []RedactRule
+		desired  string
+		redacted []RedactedField
+	}{
+		{
+			name:  "other kind",
+			rules: []RedactRule{{Kind: "Secret", Fields: []string{"data"}}},
+		},
+		{
+			name: "field path",
+			rules: []RedactRule{{Group: "apps", Kind: "Deployment", Fields: []string{
+				"metadata.annotations.example.com/token",
+				"spec.replicas",
+			}}},
+			desired: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations: {}
+spec:
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - {name: LOG_LEVEL, value: debug}
+        - {name: API_TOKEN, value: ghp_0123456789}
+        - {name: DB_PASSWORD, value: hunter2}
+      - name: sidecar
+        env:
+        - {name: API_TOKEN, value: ghp_9876543210}
+`,
+			redacted: []RedactedField{
+				{Path: `$.metadata.annotations.example\.com/token`, Type: "string"},
+				{Path: `$.metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`, Type: "string"},
+				{Path: "$.spec.replicas", Type: "number"},
+			},
+		},
+		{
+			name: "json path",
+			rules: []RedactRule{{Group: "apps", Fields: []string{
+				"$.spec.template.spec.containers[*].env[?(@.name=='API_TOKEN')].value",
+				"{.spec.template.spec.containers[0].env[2]}",
+			}}},
+			desired: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    example.com/token: secret
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - {name: LOG_LEVEL, value: debug}
+        - {name: API_TOKEN}
+      - name: sidecar
+        env:
+        - {name: API_TOKEN}
+`,
+			redacted: []RedactedField{
+				{Path: `$.metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`, Type: "string"},
+				{Path: "$.spec.template.spec.containers[0].env[1].value", Type: "string"},
+				{Path: "$.spec.template.spec.containers[0].env[2]", Type: "object"},
+				{Path: "$.spec.template.spec.containers[1].env[0].value", Type: "string"},
+			},
+		},
+		{
+			name: "recursive descent",
+			rules: []RedactRule{{Group: "apps", Fields: []string{
+				"$..env[?(@.name!='LOG_LEVEL')].value",
+			}}},
+			desired: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    example.com/token: secret
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - {name: LOG_LEVEL, value: debug}
+        - {name: API_TOKEN}
+        - {name: DB_PASSWORD}
+      - name: sidecar
+        env:
+        - {name: API_TOKEN}
+`,
+			redacted: []RedactedField{
+				{Path: `$.metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`, Type: "string"},
+				{Path: "$.spec.template.spec.containers[0].env[1].value", Type: "string"},
+				{Path: "$.spec.template.spec.containers[0].env[2].value", Type: "string"},
+				{Path: "$.spec.template.spec.containers[1].env[0].value", Type: "string"},
+			},
+		},
+		{
+			name: "value pattern",
+			rules: []RedactRule{{Group: "apps", Kind: "Deployment", ValuePattern: "^ghp_", Fields: []string{
+				"spec.template.spec.containers.*.env.*.value",
+			}}},
+			desired: `
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: app
+  annotations:
+    example.com/token: secret
+spec:
+  replicas: 2
+  template:
+    spec:
+      containers:
+      - name: app
+        env:
+        - {name: LOG_LEVEL, value: debug}
+        - {name: API_TOKEN}
+        - {name: DB_PASSWORD, value: hunter2}
+      - name: sidecar
+        env:
+        - {name: API_TOKEN}
+`,
+			redacted: []RedactedField{
+				{Path: `$.metadata.annotations.kubectl\.kubernetes\.io/last-applied-configuration`, Type: "string"},
+				{Path: "$.spec.template.spec.containers[0].env[1].value", Type: "string"},
+				{Path: "$.spec.template.spec.containers[1].env[0].value", Type: "string"},
+			},
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			original, err := yaml.YAMLToJSON([]byte(redactTestData))
+			if err != nil {
+				t.Fatalf("✗ failed to convert object: %v", err)
+			}
+			obj := &RecycledObject{Group: "apps", Version: "v1", Kind: "Deployment"}
+			if err := obj.SetData(original, EncodingNone); err != nil {
+				t.Fatalf("✗ failed to set data: %v", err)
+			}
+			if err := obj.Redact(tt.rules); err != nil {
+				t.Fatalf("✗ failed to redact: %v", err)
+			}
+			if !reflect.DeepEqual(obj.Redacted, tt.redacted) {
+				t.Errorf("✗ expected redacted %v, got %v", tt.redacted, obj.Redacted)
+			}
+
+			desired := original
+			if tt.desired != "" {
+				if desired, err = yaml.YAMLToJSON([]byte(tt.desired)); err != nil {
+					t.Fatalf("✗ failed to convert desired object: %v", err)
+				}
+			}
+			data, err := obj.Data()
+			if err != nil {
+				t.Fatalf("✗ failed to get data: %v", err)
+			}
+			if !jsonEqual(t, data, desired) {
+				t.Errorf("✗ expected %s, got %s", desired, data)
+			}
+
+			// setting the redacted fields in order restores the object
+			var content, values map[string]any
+			_ = json.Unmarshal(data, &content)
+			_ = json.Unmarshal(original, &values)
+			for _, field := range obj.Redacted {
+				path, err := parseConcretePath(field.Path)
+				if err != nil {
+					t.Fatalf("✗ failed to parse %s: %v", field.Path, err)
+				}
+				value, _ := path.get(values)
+				input, _ := json.Marshal(value)
+				if s, ok := value.(string); ok {
+					input = []byte(s)
+				}
+				if err := SetRedactedField(content, field, string(input)); err != nil {
+					t.Fatalf("✗ failed to set %s: %v", field.Path, err)
+				}
+			}
+			restored, _ := json.Marshal(content)
+			if !jsonEqual(t, restored, original) {
+				t.Errorf("✗ expected restored %s, got %s", original, restored)
+			}
+		})
+	}
+}
+
+func TestRecycledObjectRedactIdentityFields(t *testing.T) {
+	// rules created before they were validated fail instead of removing the
+	// object or its identity
+	for _, field := range []string{"$.", "*", "kind"} {
+		t.Run(field, func(t *testing.T) {
+			original, err := yaml.YAMLToJSON([]byte(redactTestData))
+			if err != nil {
+				t.Fatalf("✗ failed to convert object: %v", err)
+			}
+			obj := &RecycledObject{Group: "apps", Version: "v1", Kind: "Deployment"}
+			if err := obj.SetData(original, EncodingNone); err != nil {
+				t.Fatalf("✗ failed to set data: %v", err)
+			}
+			if err := obj.Redact([]RedactRule{{Group: "apps", Fields: []string{field}}}); err == nil {
+				t.Errorf("✗ expected an error redacting %s", field)
+			}
+		})
+	}
+}
+
+func TestRedactRuleValidate(t *testing.T) {
+	testdata := []struct {
+		name  string
+		rule  RedactRule
+		valid bool
+	}{
+		{name: "field path", rule: RedactRule{Kind: "Secret", Fields: []string{"data"}}, valid: true},
+		{name: "json path", rule: RedactRule{Fields: []string{`$.data.tls\.key`, `$.spec.env[?(@.name=="TOKEN")].value`}}, valid: true},
+		{name: "recursive descent", rule: RedactRule{Fields: []string{"$..env[?(@.name!='LOG_LEVEL')].value"}}, valid: true},
+		{name: "no fields", rule: RedactRule{Kind: "Secret"}},
+		{name: "empty segment", rule: RedactRule{Fields: []string{"spec..env"}}},
+		{name: "whole object", rule: RedactRule{Fields: []string{"$"}}},
+		{name: "root", rule: RedactRule{Fields: []string{"$."}}},
+		{name: "every field", rule: RedactRule{Fields: []string{"*"}}},
+		{name: "metadata", rule: RedactRule{Fields: []string{"$.metadata.*"}}},
+		{name: "name", rule: RedactRule{Fields: []string{"$..name"}}},
+		{name: "unclosed bracket", rule: RedactRule{Fields: []string{"$.data['key'"}}},
+		{name: "unsupported filter", rule: RedactRule{Fields: []string{"$.env[?(@.name='A')]"}}},
+		{name: "literal", rule: RedactRule{Fields: []string{"$.data 'key'"}}},
+		{name: "invalid pattern", rule: RedactRule{Fields: []string{"data"}, ValuePattern: "("}},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.rule.Validate(); (err == nil) != tt.valid {
+				t.Errorf("✗ expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestFieldPathString(t *testing.T) {
+	testdata := []struct {
+		path    fieldPath
+		desired string
+	}{
+		{path: fieldPath{"spec", "containers", 0, "env"}, desired: "$.spec.containers[0].env"},
+		{path: fieldPath{"metadata", "annotations", "example.com/token"}, desired: `$.metadata.annotations.example\.com/token`},
+		{path: fieldPath{"data", "a b[0]*"}, desired: `$.data.a\ b\[0\]\*`},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.desired, func(t *testing.T) {
+			if s := tt.path.String(); s != tt.desired {
+				t.Errorf("✗ expected %s, got %s", tt.desired, s)
+			}
+			path, err := parseConcretePath(tt.desired)
+			if err != nil {
+				t.Fatalf("✗ failed to parse %s: %v", tt.desired, err)
+			}
+			if !reflect.DeepEqual(path, tt.path) {
+				t.Errorf("✗ expected %v, got %v", tt.path, path)
+			}
+		})
+	}
+}
+
+func jsonEqual(t *testing.T, a, b []byte) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatalf("✗ failed to unmarshal %s: %v", a, err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatalf("✗ failed to unmarshal %s: %v", b, err)
+	}
+	return reflect.DeepEqual(x, y)
+}
@@ -0,0 +1,203 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// Sanitizer removes server-populated fields from a recycled object, so it can
+// be created again.
+type Sanitizer func(obj *unstructured.Unstructured)
+
+// SanitizeRule removes fields from recycled objects of a group kind.
+type SanitizeRule struct {
+	// Group of the recycled objects, empty for the core group.
+	Group string `json:"group,omitempty"`
+	// Kind of the recycled objects, empty for all kinds.
+	Kind string `json:"kind,omitempty"`
+	// Fields are dot-separated paths of the fields to remove, such as
+	// "spec.nodeName". A "*" segment matches every item of a list or every
+	// value of a map, such as "spec.ports.*.nodePort".
+	Fields []string `json:"fields"`
+}
+
+func (r SanitizeRule) GroupKind() schema.GroupKind {
+	return schema.GroupKind{Group: r.Group, Kind: r.Kind}
+}
+
+var (
+	sanitizersMu sync.RWMutex
+	// sanitizers are keyed by group kind, the empty group kind applies to all
+	// kinds.
+	sanitizers = map[schema.GroupKind][]Sanitizer{}
+)
+
+func init() {
+	RegisterSanitizer(schema.GroupKind{}, RemoveFields(
+		"metadata.uid",
+		"metadata.resourceVersion",
+		"metadata.creationTimestamp",
+		"metadata.deletionTimestamp",
+		"metadata.deletionGracePeriodSeconds",
+		"metadata.generation",
+		"metadata.managedFields",
+		"metadata.selfLink",
+		"status",
+	))
+	RegisterSanitizer(schema.GroupKind{Kind: "Pod"}, RemoveFields(
+		"spec.nodeName",
+	))
+	RegisterSanitizer(schema.GroupKind{Kind: "Service"}, removeAssignedClusterIPs, RemoveFields(
+		"spec.healthCheckNodePort",
+	))
+	RegisterSanitizer(schema.GroupKind{Kind: "PersistentVolumeClaim"}, RemoveFields(
+		"spec.volumeName",
+		"metadata.annotations.pv.kubernetes.io/bind-completed",
+		"metadata.annotations.pv.kubernetes.io/bound-by-controller",
+		"metadata.annotations.volume.kubernetes.io/selected-node",
+	))
+	RegisterSanitizer(schema.GroupKind{Kind: "PersistentVolume"}, RemoveFields(
+		"spec.claimRef.uid",
+		"spec.claimRef.resourceVersion",
+		"metadata.annotations.pv.kubernetes.io/bound-by-controller",
+	))
+	RegisterSanitizer(schema.GroupKind{Group: "batch", Kind: "Job"}, RemoveFields(
+		"spec.selector",
+		"spec.template.metadata.labels.controller-uid",
+		"spec.template.metadata.labels.batch.kubernetes.io/controller-uid",
+	))
+}
+
+// RegisterSanitizer registers sanitizers for recycled objects of the group
+// kind, the empty group kind applies to all kinds.
+func RegisterSanitizer(gk schema.GroupKind, sanitizer ...Sanitizer) {
+	sanitizersMu.Lock()
+	defer sanitizersMu.Unlock()
+	sanitizers[gk] = append(sanitizers[gk], sanitizer...)
+}
+
+// RegisterSanitizeRules registers sanitizers removing the fields of the rules.
+func RegisterSanitizeRules(rules ...SanitizeRule) {
+	for _, rule := range rules {
+		RegisterSanitizer(rule.GroupKind(), RemoveFields(rule.Fields...))
+	}
+}
+
+// ParseSanitizeRules parses sanitize rules from YAML or JSON.
+func ParseSanitizeRules(data []byte) ([]SanitizeRule, error) {
+	var rules []SanitizeRule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ParseSanitizeRule parses a sanitize rule in the form of Kind.group:field,
+// such as "Deployment.apps:spec.paused".
+func ParseSanitizeRule(s string) (SanitizeRule, error) {
+	groupKind, field, ok := strings.Cut(s, ":")
+	if !ok || field == "" {
+		return SanitizeRule{}, fmt.Errorf("invalid sanitize rule %q, expected Kind.group:field", s)
+	}
+	gk := schema.ParseGroupKind(groupKind)
+	return SanitizeRule{Group: gk.Group, Kind: gk.Kind, Fields: []string{field}}, nil
+}
+
+// Sanitize applies the sanitizers registered for all kinds and for the group
+// kind of the object.
+func Sanitize(obj *unstructured.Unstructured) {
+	sanitizersMu.RLock()
+	defer sanitizersMu.RUnlock()
+
+	for _, sanitizer := range sanitizers[schema.GroupKind{}] {
+		sanitizer(obj)
+	}
+	if gk := obj.GroupVersionKind().GroupKind(); gk != (schema.GroupKind{}) {
+		for _, sanitizer := range sanitizers[gk] {
+			sanitizer(obj)
+		}
+	}
+}
+
+// RemoveFields returns a Sanitizer removing the fields at the dot-separated
+// paths. Map keys containing dots, such as label and annotation keys, are
+// matched as a whole by the last segments of the path.
+func RemoveFields(paths ...string) Sanitizer {
+	return func(obj *unstructured.Unstructured) {
+		for _, path := range paths {
+			removeField(obj.Object, strings.Split(path, "."))
+		}
+	}
+}
+
+// removeAssignedClusterIPs removes the cluster IPs assigned by the API server
+// and keeps "None" of headless Services, which would otherwise be restored
+// as ClusterIP Services.
+func removeAssignedClusterIPs(obj *unstructured.Unstructured) {
+	if clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); clusterIP != corev1.ClusterIPNone {
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+	}
+	if clusterIPs, _, _ := unstructured.NestedStringSlice(obj.Object, "spec", "clusterIPs"); !slices.Equal(clusterIPs, []string{corev1.ClusterIPNone}) {
+		unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+	}
+}
+
+func removeField(obj any, path []string) {
+	if len(path) == 0 {
+		return
+	}
+
+	switch o := obj.(type) {
+	case map[string]any:
+		if path[0] == "*" {
+			for _, v := range o {
+				removeField(v, path[1:])
+			}
+			return
+		}
+		// The rest of the path may be a key containing dots.
+		if key := strings.Join(path, "."); len(path) > 1 {
+			if _, ok := o[key]; ok {
+				delete(o, key)
+				return
+			}
+		}
+		if len(path) == 1 {
+			delete(o, path[0])
+			return
+		}
+		if v, ok := o[path[0]]; ok {
+			removeField(v, path[1:])
+		}
+	case []any:
+		if path[0] != "*" {
+			return
+		}
+		for _, v := range o {
+			removeField(v, path[1:])
+		}
+	}
+}
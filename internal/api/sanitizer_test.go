@@ -0,0 +1,197 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+func TestSanitize(t *testing.T) {
+	testdata := []struct {
+		name    string
+		obj     string
+		desired string
+	}{
+		{
+			name: "pod",
+			obj: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+  uid: 6b1c1f6e-0000-0000-0000-000000000000
+  resourceVersion: "42"
+  creationTimestamp: "2025-01-01T00:00:00Z"
+  managedFields: [{manager: kubectl}]
+spec:
+  nodeName: node-1
+  containers: [{name: foo, image: nginx}]
+status:
+  phase: Running
+`,
+			desired: `
+apiVersion: v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  containers: [{name: foo, image: nginx}]
+`,
+		},
+		{
+			name: "service",
+			obj: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  clusterIP: 10.0.0.1
+  clusterIPs: [10.0.0.1]
+  ports: [{port: 80, nodePort: 30080}]
+`,
+			desired: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  ports: [{port: 80, nodePort: 30080}]
+`,
+		},
+		{
+			name: "headless-service",
+			obj: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  clusterIP: None
+  clusterIPs: [None]
+  ports: [{port: 80}]
+`,
+			desired: `
+apiVersion: v1
+kind: Service
+metadata:
+  name: foo
+spec:
+  clusterIP: None
+  clusterIPs: [None]
+  ports: [{port: 80}]
+`,
+		},
+		{
+			name: "pvc-annotations",
+			obj: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: foo
+  annotations:
+    pv.kubernetes.io/bind-completed: "yes"
+    foo: bar
+spec:
+  volumeName: pvc-1
+`,
+			desired: `
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: foo
+  annotations:
+    foo: bar
+spec: {}
+`,
+		},
+		{
+			name: "other-group-not-sanitized",
+			obj: `
+apiVersion: example.com/v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  nodeName: node-1
+`,
+			desired: `
+apiVersion: example.com/v1
+kind: Pod
+metadata:
+  name: foo
+spec:
+  nodeName: node-1
+`,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			obj, desired := &unstructured.Unstructured{}, &unstructured.Unstructured{}
+			if err := yaml.Unmarshal([]byte(tt.obj), &obj.Object); err != nil {
+				t.Fatalf("✗ failed to unmarshal object: %v", err)
+			}
+			if err := yaml.Unmarshal([]byte(tt.desired), &desired.Object); err != nil {
+				t.Fatalf("✗ failed to unmarshal desired object: %v", err)
+			}
+
+			Sanitize(obj)
+			if !reflect.DeepEqual(obj.Object, desired.Object) {
+				t.Errorf("✗ expected %v, got %v", desired.Object, obj.Object)
+			}
+		})
+	}
+}
+
+func TestRemoveFields(t *testing.T) {
+	obj := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal([]byte(`
+spec:
+  ports: [{port: 80, nodePort: 30080}, {port: 443, nodePort: 30443}]
+`), &obj.Object); err != nil {
+		t.Fatalf("✗ failed to unmarshal object: %v", err)
+	}
+
+	RemoveFields("spec.ports.*.nodePort")(obj)
+
+	ports, _, _ := unstructured.NestedSlice(obj.Object, "spec", "ports")
+	for _, port := range ports {
+		if _, ok := port.(map[string]any)["nodePort"]; ok {
+			t.Errorf("✗ expected nodePort removed, got %v", port)
+		}
+	}
+}
+
+func TestParseSanitizeRule(t *testing.T) {
+	rule, err := ParseSanitizeRule("Deployment.apps:spec.paused")
+	if err != nil {
+		t.Fatalf("✗ failed to parse sanitize rule: %v", err)
+	}
+	desired := SanitizeRule{Group: "apps", Kind: "Deployment", Fields: []string{"spec.paused"}}
+	if !reflect.DeepEqual(rule, desired) {
+		t.Errorf("✗ expected %v, got %v", desired, rule)
+	}
+
+	if _, err := ParseSanitizeRule("Deployment.apps"); err == nil {
+		t.Errorf("✗ expected error for rule without field")
+	}
+}
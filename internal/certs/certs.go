@@ -0,0 +1,343 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
+)
+
+// Options of the certificates of krb-webhook.
+type Options struct {
+	// Validity of the serving certificate.
+	Validity time.Duration
+	// CAValidity of the CA certificate signing the serving certificate.
+	CAValidity time.Duration
+	// RenewBefore is how long before they expire certificates are renewed.
+	RenewBefore time.Duration
+}
+
+// DefaultOptions issue serving certificates for a year under a CA valid for
+// ten years, renewing them 30 days before they expire.
+var DefaultOptions = Options{
+	Validity:    time.Hour * 24 * 365,
+	CAValidity:  time.Hour * 24 * 365 * 10,
+	RenewBefore: time.Hour * 24 * 30,
+}
+
+// Validate rejects options renewing certificates as soon as they are issued.
+func (o Options) Validate() error {
+	if o.Validity <= 0 || o.CAValidity <= 0 || o.RenewBefore <= 0 {
+		return fmt.Errorf("certificate validity and renewal must be positive")
+	}
+	if o.RenewBefore >= o.Validity || o.RenewBefore >= o.CAValidity {
+		return fmt.Errorf("certificates must be renewed before %s, which is not shorter than their validity", o.RenewBefore)
+	}
+	return nil
+}
+
+// Bundle is the serving certificate of krb-webhook and the CA it is signed
+// with, PEM encoded as in the krb-webhook-tls Secret.
+type Bundle struct {
+	// CABundle holds the CA certificates clients of the webhook trust, the
+	// current CA first followed by earlier ones that haven't expired, so
+	// clients keep trusting the serving certificate while the CA rotates.
+	CABundle []byte
+	// CAKey is the key of the current CA, empty for Secrets created by
+	// earlier versions whose serving certificate was self-signed.
+	CAKey []byte
+	Cert  []byte
+	Key   []byte
+}
+
+// Generate issues a new CA and a serving certificate signed with it.
+func Generate(opts Options, now time.Time) (*Bundle, error) {
+	b, _, err := (&Bundle{}).Renew(opts, now)
+	return b, err
+}
+
+// FromSecret reads the bundle from the krb-webhook-tls Secret. Secrets
+// created by earlier versions have no CA, their self-signed serving
+// certificate is its own CA.
+func FromSecret(secret *corev1.Secret) *Bundle {
+	b := &Bundle{
+		CABundle: secret.Data[corev1.ServiceAccountRootCAKey],
+		CAKey:    secret.Data[consts.WebhookTLSCAKeyKey],
+		Cert:     secret.Data[corev1.TLSCertKey],
+		Key:      secret.Data[corev1.TLSPrivateKeyKey],
+	}
+	if len(b.CABundle) == 0 {
+		b.CABundle = b.Cert
+	}
+	return b
+}
+
+// Secret returns the krb-webhook-tls Secret holding the bundle.
+func (b *Bundle) Secret() *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.WebhookTLSCertSecretName,
+			Namespace: consts.WebhookNamespace,
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.ServiceAccountRootCAKey: b.CABundle,
+			consts.WebhookTLSCAKeyKey:      b.CAKey,
+			corev1.TLSCertKey:              b.Cert,
+			corev1.TLSPrivateKeyKey:        b.Key,
+		},
+	}
+}
+
+// TLSCertificate returns the serving certificate for a tls.Config.
+func (b *Bundle) TLSCertificate() (*tls.Certificate, error) {
+	cert, err := tls.X509KeyPair(b.Cert, b.Key)
+	if err != nil {
+		return nil, err
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, err
+		}
+	}
+	return &cert, nil
+}
+
+// NotAfter returns when the serving certificate expires, the zero time if it
+// can't be parsed.
+func (b *Bundle) NotAfter() time.Time {
+	cert, err := b.TLSCertificate()
+	if err != nil {
+		return time.Time{}
+	}
+	return cert.Leaf.NotAfter
+}
+
+// Due reports whether the CA and the serving certificate need to be renewed:
+// the CA when it has no key or expires within the renewal period, the
+// serving certificate when it expires within the renewal period or isn't
+// signed with the current CA.
+func (b *Bundle) Due(opts Options, now time.Time) (ca, cert bool) {
+	deadline := now.Add(opts.RenewBefore)
+	caCert, _, err := b.ca()
+	if err != nil || caCert.NotAfter.Before(deadline) {
+		return true, true
+	}
+	servingCert, err := b.TLSCertificate()
+	if err != nil || servingCert.Leaf.NotAfter.Before(deadline) || servingCert.Leaf.CheckSignatureFrom(caCert) != nil ||
+		servingCert.Leaf.VerifyHostname(consts.WebhookDNSName) != nil {
+		return false, true
+	}
+	return false, false
+}
+
+// Renew returns the bundle with the due certificates renewed, and whether
+// any was. A renewed CA is added in front of the CA bundle.
+func (b *Bundle) Renew(opts Options, now time.Time) (*Bundle, bool, error) {
+	renewCA, renewCert := b.Due(opts, now)
+	if !renewCA && !renewCert {
+		return b, false, nil
+	}
+
+	result := &Bundle{CABundle: b.CABundle, CAKey: b.CAKey}
+	if renewCA {
+		caCert, caKey, err := generateCA(opts, now)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to generate CA: %w", err)
+		}
+		result.CABundle = append(caCert, unexpired(b.CABundle, now)...)
+		result.CAKey = caKey
+	}
+	caCert, caKey, err := result.ca()
+	if err != nil {
+		return nil, false, err
+	}
+	if result.Cert, result.Key, err = generateServingCert(caCert, caKey, opts, now); err != nil {
+		return nil, false, fmt.Errorf("failed to generate serving certificate: %w", err)
+	}
+	return result, true, nil
+}
+
+// ca returns the current CA, the first in the bundle, and its key.
+func (b *Bundle) ca() (*x509.Certificate, crypto.Signer, error) {
+	if len(b.CAKey) == 0 {
+		return nil, nil, errors.New("no CA key")
+	}
+	block, _ := pem.Decode(b.CABundle)
+	if block == nil {
+		return nil, nil, errors.New("no CA certificate")
+	}
+	caCert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyBlock, _ := pem.Decode(b.CAKey)
+	if keyBlock == nil {
+		return nil, nil, errors.New("invalid CA key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, errors.New("CA key can't sign")
+	}
+	return caCert, signer, nil
+}
+
+// unexpired returns the PEM encoded certificates of the bundle that haven't
+// expired.
+func unexpired(bundle []byte, now time.Time) []byte {
+	var result []byte
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		if cert, err := x509.ParseCertificate(block.Bytes); err == nil && now.Before(cert.NotAfter) {
+			result = append(result, pem.EncodeToMemory(block)...)
+		}
+	}
+	return result
+}
+
+func generateCA(opts Options, now time.Time) ([]byte, []byte, error) {
+	template := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "krb-webhook-ca@" + now.UTC().Format(time.RFC3339)},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(opts.CAValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	return issue(template, nil, nil)
+}
+
+func generateServingCert(caCert *x509.Certificate, caKey crypto.Signer, opts Options, now time.Time) ([]byte, []byte, error) {
+	notAfter := now.Add(opts.Validity)
+	if caCert.NotAfter.Before(notAfter) {
+		notAfter = caCert.NotAfter
+	}
+	template := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: consts.WebhookDNSName},
+		DNSNames:    []string{consts.WebhookDNSName},
+		NotBefore:   now.Add(-time.Hour),
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	return issue(template, caCert, caKey)
+}
+
+// issue generates a key and a certificate from the template signed with the
+// parent, or self-signed if parent is nil.
+func issue(template, parent *x509.Certificate, parentKey crypto.Signer) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	if template.SerialNumber, err = rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128)); err != nil {
+		return nil, nil, err
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// Load reads the bundle from the krb-webhook-tls Secret.
+func Load(ctx context.Context, client kubernetes.Interface) (*Bundle, error) {
+	secret, err := client.CoreV1().Secrets(consts.WebhookNamespace).Get(ctx, consts.WebhookTLSCertSecretName, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return FromSecret(secret), nil
+}
+
+// Ensure reads the bundle from the krb-webhook-tls Secret, creating the
+// Secret with a generated bundle if there is none. If renew is true due
+// certificates are renewed and the Secret is updated, the returned bool
+// reports whether it was.
+func Ensure(ctx context.Context, client kubernetes.Interface, opts Options, renew bool) (*Bundle, bool, error) {
+	secrets := client.CoreV1().Secrets(consts.WebhookNamespace)
+	var result *Bundle
+	var renewed bool
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := secrets.Get(ctx, consts.WebhookTLSCertSecretName, metav1.GetOptions{})
+		if k8serrors.IsNotFound(err) {
+			if result, err = Generate(opts, time.Now()); err != nil {
+				return err
+			}
+			if _, err := secrets.Create(ctx, result.Secret(), metav1.CreateOptions{}); k8serrors.IsAlreadyExists(err) {
+				// created concurrently, read it on retry
+				return k8serrors.NewConflict(corev1.Resource("secrets"), consts.WebhookTLSCertSecretName, err)
+			} else if err != nil {
+				return err
+			}
+			renewed = true
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		result = FromSecret(secret)
+		if !renew {
+			return nil
+		}
+		if result, renewed, err = result.Renew(opts, time.Now()); err != nil || !renewed {
+			return err
+		}
+		updated := result.Secret()
+		updated.ObjectMeta = secret.ObjectMeta
+		_, err = secrets.Update(ctx, updated, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to ensure secret [%s]: %w", consts.WebhookTLSCertSecretName, err)
+	}
+	return result, renewed, nil
+}
+
+// Equal reports whether the bundles hold the same certificates.
+func (b *Bundle) Equal(other *Bundle) bool {
+	return other != nil && bytes.Equal(b.CABundle, other.CABundle) && bytes.Equal(b.Cert, other.Cert) && bytes.Equal(b.Key, other.Key)
+}
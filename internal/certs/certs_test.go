@@ -0,0 +1,144 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certs
+
+import (
+	"bytes"
+	"context"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+	certutil "k8s.io/client-go/util/cert"
+)
+
+func TestBundleRenew(t *testing.T) {
+	now := time.Now()
+	generated, err := Generate(DefaultOptions, now)
+	if err != nil {
+		t.Fatalf("✗ failed to generate bundle: %v", err)
+	}
+	legacyCert, legacyKey, err := certutil.GenerateSelfSignedCertKey(consts.WebhookDNSName, nil, []string{consts.WebhookDNSName})
+	if err != nil {
+		t.Fatalf("✗ failed to generate legacy cert: %v", err)
+	}
+	legacy := FromSecret(&corev1.Secret{Data: map[string][]byte{corev1.TLSCertKey: legacyCert, corev1.TLSPrivateKeyKey: legacyKey}})
+
+	testdata := []struct {
+		name      string
+		bundle    *Bundle
+		at        time.Time
+		renewCA   bool
+		renewCert bool
+		caCount   int
+	}{
+		{name: "fresh", bundle: generated, at: now, caCount: 1},
+		{name: "serving cert due", bundle: generated, at: now.Add(DefaultOptions.Validity - DefaultOptions.RenewBefore + time.Hour), renewCert: true, caCount: 1},
+		{name: "ca due", bundle: generated, at: now.Add(DefaultOptions.CAValidity - DefaultOptions.RenewBefore + time.Hour), renewCA: true, renewCert: true, caCount: 2},
+		{name: "ca expired", bundle: generated, at: now.Add(DefaultOptions.CAValidity + time.Hour), renewCA: true, renewCert: true, caCount: 1},
+		// the legacy cert is a chain of the serving cert and its CA, both kept
+		{name: "legacy", bundle: legacy, at: now, renewCA: true, renewCert: true, caCount: 3},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			renewCA, renewCert := tt.bundle.Due(DefaultOptions, tt.at)
+			if renewCA != tt.renewCA || renewCert != tt.renewCert {
+				t.Errorf("✗ expected due %v, %v, got %v, %v", tt.renewCA, tt.renewCert, renewCA, renewCert)
+			}
+			renewed, ok, err := tt.bundle.Renew(DefaultOptions, tt.at)
+			if err != nil {
+				t.Fatalf("✗ failed to renew: %v", err)
+			}
+			if ok != (tt.renewCA || tt.renewCert) {
+				t.Errorf("✗ expected renewed %v, got %v", tt.renewCA || tt.renewCert, ok)
+			}
+			if ca, cert := renewed.Due(DefaultOptions, tt.at); ca || cert {
+				t.Errorf("✗ expected renewed bundle not due, got %v, %v", ca, cert)
+			}
+			if bytes.Equal(renewed.CAKey, tt.bundle.CAKey) == tt.renewCA {
+				t.Errorf("✗ expected CA renewed %v", tt.renewCA)
+			}
+
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(renewed.CABundle) {
+				t.Fatalf("✗ invalid CA bundle")
+			}
+			if count := countCerts(renewed.CABundle); count != tt.caCount {
+				t.Errorf("✗ expected %d CA certificates, got %d", tt.caCount, count)
+			}
+			cert, err := renewed.TLSCertificate()
+			if err != nil {
+				t.Fatalf("✗ invalid serving cert: %v", err)
+			}
+			if _, err := cert.Leaf.Verify(x509.VerifyOptions{Roots: pool, DNSName: consts.WebhookDNSName, CurrentTime: tt.at}); err != nil {
+				t.Errorf("✗ failed to verify serving cert: %v", err)
+			}
+		})
+	}
+}
+
+func TestEnsure(t *testing.T) {
+	client := fake.NewClientset()
+	created, renewed, err := Ensure(context.Background(), client, DefaultOptions, false)
+	if err != nil || !renewed {
+		t.Fatalf("✗ expected bundle to be created, got %v, %v", renewed, err)
+	}
+	loaded, renewed, err := Ensure(context.Background(), client, DefaultOptions, true)
+	if err != nil || renewed || !loaded.Equal(created) {
+		t.Errorf("✗ expected created bundle to be loaded, got renewed %v, %v", renewed, err)
+	}
+
+	// the legacy self-signed cert is replaced on renewal
+	legacyCert, legacyKey, _ := certutil.GenerateSelfSignedCertKey(consts.WebhookDNSName, nil, []string{consts.WebhookDNSName})
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: consts.WebhookTLSCertSecretName, Namespace: consts.WebhookNamespace},
+		Data:       map[string][]byte{corev1.TLSCertKey: legacyCert, corev1.TLSPrivateKeyKey: legacyKey},
+	}
+	client = fake.NewClientset(secret)
+	if loaded, _, err = Ensure(context.Background(), client, DefaultOptions, false); err != nil || !bytes.Equal(loaded.Cert, legacyCert) {
+		t.Errorf("✗ expected legacy cert to be loaded, got %v", err)
+	}
+	if _, renewed, err = Ensure(context.Background(), client, DefaultOptions, true); err != nil || !renewed {
+		t.Errorf("✗ expected legacy cert to be renewed, got %v, %v", renewed, err)
+	}
+	loaded, err = Load(context.Background(), client)
+	if err != nil || len(loaded.CAKey) == 0 || bytes.Equal(loaded.Cert, legacyCert) {
+		t.Errorf("✗ expected renewed bundle in secret, got %v", err)
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	if err := DefaultOptions.Validate(); err != nil {
+		t.Errorf("✗ expected default options to be valid, got %v", err)
+	}
+	if err := (Options{Validity: time.Hour, CAValidity: time.Hour * 24, RenewBefore: time.Hour}).Validate(); err == nil {
+		t.Errorf("✗ expected renewal as long as validity to be invalid")
+	}
+}
+
+func countCerts(bundle []byte) int {
+	count := 0
+	for block, rest := pem.Decode(bundle); block != nil; block, rest = pem.Decode(rest) {
+		count++
+	}
+	return count
+}
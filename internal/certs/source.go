@@ -0,0 +1,125 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// CASourceManaged is the CA krb-controller issues and rotates in the
+	// krb-webhook-tls Secret.
+	CASourceManaged = "managed"
+	// CASourceSecret is a CA bundle in a Secret, such as one issued by
+	// cert-manager.
+	CASourceSecret = "secret"
+	// CASourceConfigMap is a CA bundle in a ConfigMap, such as one
+	// distributed by trust-manager.
+	CASourceConfigMap = "configmap"
+	// CASourceCertManager leaves the CA bundle to the CA injector of
+	// cert-manager, which injects the CA of the Certificate.
+	CASourceCertManager = "cert-manager"
+
+	// InjectCAFromAnnotation tells the CA injector of cert-manager to inject
+	// the CA of the namespace/name Certificate into the webhooks.
+	InjectCAFromAnnotation = "cert-manager.io/inject-ca-from"
+)
+
+// CASource is where krb-controller takes the CA bundle of the webhook
+// configurations from.
+type CASource struct {
+	// Kind of the source. One of: managed|secret|configmap|cert-manager
+	Kind string
+	// Namespace and Name of the Secret, ConfigMap or cert-manager
+	// Certificate.
+	Namespace string
+	Name      string
+	// Key of the CA bundle in the Secret or ConfigMap, defaults to ca.crt.
+	Key string
+}
+
+// ParseCASource parses a CA source in the form of managed,
+// secret:namespace/name[:key], configmap:namespace/name[:key] or
+// cert-manager:namespace/certificate.
+func ParseCASource(s string) (CASource, error) {
+	if s == "" || s == CASourceManaged {
+		return CASource{Kind: CASourceManaged}, nil
+	}
+	kind, ref, _ := strings.Cut(s, ":")
+	ref, key, _ := strings.Cut(ref, ":")
+	namespace, name, ok := strings.Cut(ref, "/")
+	if !ok || namespace == "" || name == "" {
+		return CASource{}, fmt.Errorf("invalid CA source %q, expected managed, secret:namespace/name[:key], configmap:namespace/name[:key] or cert-manager:namespace/certificate", s)
+	}
+	switch kind {
+	case CASourceSecret, CASourceConfigMap:
+		if key == "" {
+			key = corev1.ServiceAccountRootCAKey
+		}
+	case CASourceCertManager:
+		if key != "" {
+			return CASource{}, fmt.Errorf("invalid CA source %q, cert-manager sources have no key", s)
+		}
+	default:
+		return CASource{}, fmt.Errorf("invalid CA source kind %q, must be one of: managed|secret|configmap|cert-manager", kind)
+	}
+	return CASource{Kind: kind, Namespace: namespace, Name: name, Key: key}, nil
+}
+
+func (s CASource) String() string {
+	switch s.Kind {
+	case CASourceManaged:
+		return s.Kind
+	case CASourceCertManager:
+		return s.Kind + ":" + s.Namespace + "/" + s.Name
+	}
+	return s.Kind + ":" + s.Namespace + "/" + s.Name + ":" + s.Key
+}
+
+// LoadCABundle reads the CA bundle from the Secret or ConfigMap of the
+// source.
+func LoadCABundle(ctx context.Context, client kubernetes.Interface, source CASource) ([]byte, error) {
+	var caBundle []byte
+	switch source.Kind {
+	case CASourceSecret:
+		secret, err := client.CoreV1().Secrets(source.Namespace).Get(ctx, source.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get secret [%s/%s]: %w", source.Namespace, source.Name, err)
+		}
+		caBundle = secret.Data[source.Key]
+	case CASourceConfigMap:
+		configMap, err := client.CoreV1().ConfigMaps(source.Namespace).Get(ctx, source.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get configmap [%s/%s]: %w", source.Namespace, source.Name, err)
+		}
+		caBundle = []byte(configMap.Data[source.Key])
+		if len(caBundle) == 0 {
+			caBundle = configMap.BinaryData[source.Key]
+		}
+	default:
+		return nil, fmt.Errorf("CA source %s has no CA bundle to load", source.String())
+	}
+	if len(caBundle) == 0 {
+		return nil, fmt.Errorf("no CA bundle in key %s of %s [%s/%s]", source.Key, source.Kind, source.Namespace, source.Name)
+	}
+	return caBundle, nil
+}
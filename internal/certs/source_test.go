@@ -0,0 +1,97 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package certs
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestParseCASource(t *testing.T) {
+	testdata := []struct {
+		name    string
+		source  string
+		desired CASource
+		invalid bool
+	}{
+		{name: "default", desired: CASource{Kind: CASourceManaged}},
+		{name: "managed", source: "managed", desired: CASource{Kind: CASourceManaged}},
+		{name: "secret", source: "secret:krb-system/krb-webhook-cert", desired: CASource{Kind: CASourceSecret, Namespace: "krb-system", Name: "krb-webhook-cert", Key: "ca.crt"}},
+		{name: "configmap with key", source: "configmap:krb-system/trust:bundle.pem", desired: CASource{Kind: CASourceConfigMap, Namespace: "krb-system", Name: "trust", Key: "bundle.pem"}},
+		{name: "cert-manager", source: "cert-manager:krb-system/krb-webhook", desired: CASource{Kind: CASourceCertManager, Namespace: "krb-system", Name: "krb-webhook"}},
+		{name: "cert-manager with key", source: "cert-manager:krb-system/krb-webhook:ca.crt", invalid: true},
+		{name: "no namespace", source: "secret:krb-webhook-cert", invalid: true},
+		{name: "unknown kind", source: "vault:krb-system/krb-webhook", invalid: true},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			source, err := ParseCASource(tt.source)
+			if (err != nil) != tt.invalid {
+				t.Fatalf("✗ expected invalid %v, got %v", tt.invalid, err)
+			}
+			if source != tt.desired {
+				t.Errorf("✗ expected %v, got %v", tt.desired, source)
+			}
+			if !tt.invalid {
+				if reparsed, _ := ParseCASource(source.String()); reparsed != source {
+					t.Errorf("✗ expected %s to parse to %v, got %v", source.String(), source, reparsed)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCABundle(t *testing.T) {
+	client := fake.NewClientset(
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "krb-webhook-cert", Namespace: "krb-system"},
+			Data:       map[string][]byte{"ca.crt": []byte("secret-ca")},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "trust", Namespace: "krb-system"},
+			Data:       map[string]string{"bundle.pem": "configmap-ca"},
+		},
+	)
+
+	testdata := []struct {
+		name    string
+		source  CASource
+		desired string
+	}{
+		{name: "secret", source: CASource{Kind: CASourceSecret, Namespace: "krb-system", Name: "krb-webhook-cert", Key: "ca.crt"}, desired: "secret-ca"},
+		{name: "configmap", source: CASource{Kind: CASourceConfigMap, Namespace: "krb-system", Name: "trust", Key: "bundle.pem"}, desired: "configmap-ca"},
+		{name: "missing key", source: CASource{Kind: CASourceSecret, Namespace: "krb-system", Name: "krb-webhook-cert", Key: "tls.crt"}},
+		{name: "missing secret", source: CASource{Kind: CASourceSecret, Namespace: "krb-system", Name: "other", Key: "ca.crt"}},
+		{name: "cert-manager", source: CASource{Kind: CASourceCertManager, Namespace: "krb-system", Name: "krb-webhook"}},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			caBundle, err := LoadCABundle(context.Background(), client, tt.source)
+			if (err != nil) != (tt.desired == "") {
+				t.Fatalf("✗ unexpected error %v", err)
+			}
+			if string(caBundle) != tt.desired {
+				t.Errorf("✗ expected %q, got %q", tt.desired, caBundle)
+			}
+		})
+	}
+}
@@ -51,5 +51,6 @@ type RecyclePolicyInterface interface {
 	Get(ctx context.Context, name string, opts client.GetOptions) (*api.RecyclePolicy, error)
 	List(ctx context.Context, opts client.ListOptions) (*api.RecyclePolicyList, error)
 	Update(ctx context.Context, obj *api.RecyclePolicy, opts client.UpdateOptions) error
+	UpdateStatus(ctx context.Context, obj *api.RecyclePolicy, opts client.SubResourceUpdateOptions) error
 	Delete(ctx context.Context, name string, opts client.DeleteOptions) error
 }
@@ -0,0 +1,100 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/internal/encryption"
+	"github.com/ketches/kube-recycle-bin/internal/storage"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ErrNoAccess is returned when the user may not decrypt the payload of a
+// RecycleItem.
+var ErrNoAccess = errors.New("no access to the encrypted payload")
+
+var encryptor *encryption.Encryptor
+
+// LoadPayload loads the payload of the RecycleItem from the storage backend
+// it is stored in, if it is not stored in the RecycleItem, and decrypts it.
+func LoadPayload(ctx context.Context, recycleItem *api.RecycleItem) error {
+	if ref := recycleItem.PayloadRef(); ref != nil && len(recycleItem.Object.Raw) == 0 {
+		backend, err := storage.Open(ctx, kube.Client(), ref.Backend, consts.PayloadReaderSecretName)
+		if err != nil {
+			return err
+		}
+		payload, err := backend.Get(ctx, ref.Key)
+		if err != nil {
+			return fmt.Errorf("failed to load payload of RecycleItem [%s] from %s: %w", recycleItem.Name, ref.Backend, err)
+		}
+		if err := recycleItem.Object.Load(payload); err != nil {
+			return err
+		}
+	}
+	return decryptPayload(ctx, &recycleItem.Object)
+}
+
+// decryptPayload decrypts the payload of the recycled object if the user may
+// get the object in its namespace and read the encryption key. The access
+// review only keeps users from decrypting objects they couldn't get, anyone
+// who can read the krb-encryption Secret can decrypt every payload with it,
+// so access to the Secret is what protects encrypted payloads.
+func decryptPayload(ctx context.Context, recycledObj *api.RecycledObject) error {
+	if recycledObj.Encryption == nil {
+		return nil
+	}
+
+	review, err := kube.Client().AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: recycledObj.Namespace,
+				Verb:      "get",
+				Group:     recycledObj.Group,
+				Resource:  recycledObj.Resource,
+				Name:      recycledObj.Name,
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to review access to %s [%s]: %w", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+	}
+	if !review.Status.Allowed {
+		return fmt.Errorf("%w: not allowed to get %s [%s]", ErrNoAccess, recycledObj.GroupResource().String(), recycledObj.Key())
+	}
+
+	if encryptor == nil {
+		config, err := encryption.LoadConfig(ctx, kube.Client())
+		if k8serrors.IsForbidden(err) {
+			return fmt.Errorf("%w: not allowed to read the encryption key", ErrNoAccess)
+		}
+		if err != nil {
+			return err
+		}
+		if encryptor, err = encryption.NewEncryptor(config); err != nil {
+			return err
+		}
+	}
+	return encryptor.Decrypt(ctx, recycledObj)
+}
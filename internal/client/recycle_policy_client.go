@@ -77,6 +77,10 @@ func (c *recyclePolicyClient) Update(ctx context.Context, obj *api.RecyclePolicy
 	return nil
 }
 
+func (c *recyclePolicyClient) UpdateStatus(ctx context.Context, obj *api.RecyclePolicy, opts client.SubResourceUpdateOptions) error {
+	return c.Client.Status().Update(ctx, obj, &opts)
+}
+
 func (c *recyclePolicyClient) Delete(ctx context.Context, name string, opts client.DeleteOptions) error {
 	if err := c.Client.Delete(ctx, &api.RecyclePolicy{
 		ObjectMeta: metav1.ObjectMeta{
@@ -20,6 +20,7 @@ import (
 	"context"
 	"slices"
 
+	"github.com/ketches/kube-recycle-bin/internal/api"
 	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
@@ -130,7 +131,11 @@ func RecyclePolicyGroupResource(cmd *cobra.Command, args []string, toComplete st
 
 	var result []string
 	for _, item := range list.Items {
-		result = append(result, item.Target.GroupResource().String())
+		for _, tr := range item.Target.TargetResources() {
+			if !tr.IsWildcard() {
+				result = append(result, tr.GroupResource().String())
+			}
+		}
 	}
 
 	return result, cobra.ShellCompDirectiveNoFileComp
@@ -161,14 +166,14 @@ func RecyclePolicy(cmd *cobra.Command, args []string, toComplete string) ([]stri
 	labelSet := labels.Set{}
 	targetNamespace, _ := cmd.Flags().GetString("target-namespace")
 	if targetNamespace != "" {
-		labelSet["krb.ketches.cn/target-namespace"] = targetNamespace
+		labelSet[api.TargetNamespaceLabelPrefix+targetNamespace] = "true"
 	}
 	targetResource, _ := cmd.Flags().GetString("target-resource")
 	if targetResource != "" {
 		if gvr, err := kube.GetPreferredGroupVersionResourceFor(targetResource); err != nil {
 			tlog.Printf("✗ failed to get preferred group version resource: %v", err)
 		} else {
-			labelSet["krb.ketches.cn/target-gr"] = gvr.GroupResource().String()
+			labelSet[api.TargetGroupResourceLabel] = gvr.GroupResource().String()
 		}
 	}
 
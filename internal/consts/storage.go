@@ -0,0 +1,39 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package consts
+
+const (
+	StorageSecretName      = "krb-storage"
+	StorageSecretConfigKey = "storage.yaml"
+	WebhookPayloadsPath    = "/payloads/"
+	PayloadTokenHeader     = "X-Krb-Token"
+)
+
+const (
+	// PayloadReaderSecretName holds the token to read payloads from the
+	// payloads endpoint of krb-webhook.
+	PayloadReaderSecretName = "krb-payload-reader"
+	// PayloadDeleterSecretName holds the token to delete payloads from the
+	// payloads endpoint of krb-webhook.
+	PayloadDeleterSecretName = "krb-payload-deleter"
+	PayloadTokenSecretKey    = "token"
+)
+
+const (
+	EncryptionSecretName      = "krb-encryption"
+	EncryptionSecretConfigKey = "encryption.yaml"
+	EncryptionSecretKeyKey    = "key"
+)
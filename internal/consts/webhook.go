@@ -20,8 +20,18 @@ const (
 	WebhookNamespace          = "krb-system"
 	WebhookName               = "krb-webhook"
 	WebhookTLSCertSecretName  = "krb-webhook-tls"
+	WebhookTLSCAKeyKey        = "ca.key"
 	WebhookServicePath        = "/validate"
+	WebhookPolicyPath         = "/validate-recyclepolicy"
+	PolicyWebhookName         = "krb-policy-webhook"
+	WebhookMetricsPath        = "/metrics"
 	WebhookServiceTLSCertFile = "tls.crt"
 	WebhookServiceTLSKeyFile  = "tls.key"
 	WebhookDNSName            = "krb-webhook.krb-system.svc"
 )
+
+const (
+	ExclusionsConfigMapName = "krb-exclusions"
+	ExclusionsResourcesKey  = "resources"
+	ExclusionsNamespacesKey = "namespaces"
+)
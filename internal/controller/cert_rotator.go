@@ -0,0 +1,118 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/certs"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CertRotator renews the CA and serving certificate of krb-webhook in the
+// krb-webhook-tls Secret ahead of their expiry, and patches the CA bundle of
+// all krb webhook configurations, krb-webhook reloads the Secret by itself.
+// With an external CA source it only patches the CA bundle read from the
+// source, the certificates are renewed by whoever provides them.
+type CertRotator struct {
+	Reconciler *RecyclePolicyReconciler
+	Options    certs.Options
+	Interval   time.Duration
+}
+
+// Start checks the certificates right away and every interval until the
+// context is done.
+func (c *CertRotator) Start(ctx context.Context) error {
+	ticker := time.NewTicker(c.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.check(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *CertRotator) check(ctx context.Context) {
+	var caBundle []byte
+	if source := c.Reconciler.CASource; source.Kind == certs.CASourceManaged {
+		bundle, renewed, err := certs.Ensure(ctx, kube.Client(), c.Options, true)
+		if err != nil {
+			tlog.Errorf("✗ failed to rotate webhook certificates: %v", err)
+			return
+		}
+		if renewed {
+			tlog.Infof("✓ webhook certificates renewed, valid until %s.", bundle.NotAfter().Format(time.RFC3339))
+		}
+		caBundle = bundle.CABundle
+	} else {
+		var err error
+		if caBundle, err = certs.LoadCABundle(ctx, kube.Client(), source); err != nil {
+			tlog.Errorf("✗ failed to load CA bundle of webhooks: %v", err)
+			return
+		}
+	}
+
+	c.Reconciler.caBundle.Store(&caBundle)
+	if err := patchCABundles(ctx, c.Reconciler.Client, caBundle); err != nil {
+		tlog.Errorf("✗ failed to patch CA bundle of webhooks: %v", err)
+	}
+}
+
+// patchCABundles sets the CA bundle of every webhook in the webhook
+// configurations managed by krb-controller.
+func patchCABundles(ctx context.Context, c client.Client, caBundle []byte) error {
+	webhooks := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := c.List(ctx, webhooks, client.MatchingLabels{consts.ManagedByLabel: consts.ControllerName}); err != nil {
+		return err
+	}
+	for i := range webhooks.Items {
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			webhook := &webhooks.Items[i]
+			if err := c.Get(ctx, client.ObjectKeyFromObject(webhook), webhook); err != nil {
+				return client.IgnoreNotFound(err)
+			}
+			changed := false
+			for j := range webhook.Webhooks {
+				if !bytes.Equal(webhook.Webhooks[j].ClientConfig.CABundle, caBundle) {
+					webhook.Webhooks[j].ClientConfig.CABundle = caBundle
+					changed = true
+				}
+			}
+			if !changed {
+				return nil
+			}
+			if err := c.Update(ctx, webhook); err != nil {
+				return err
+			}
+			tlog.Infof("✓ CA bundle of webhook [%s] patched.", webhook.Name)
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
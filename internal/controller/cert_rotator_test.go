@@ -0,0 +1,104 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package controller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/certs"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPatchCABundles(t *testing.T) {
+	newWebhookConfiguration := func(name string, managed bool, caBundles ...string) *admissionregistrationv1.ValidatingWebhookConfiguration {
+		result := &admissionregistrationv1.ValidatingWebhookConfiguration{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if managed {
+			result.Labels = map[string]string{consts.ManagedByLabel: consts.ControllerName}
+		}
+		for _, caBundle := range caBundles {
+			result.Webhooks = append(result.Webhooks, admissionregistrationv1.ValidatingWebhook{
+				Name:         name + ".example.com",
+				ClientConfig: admissionregistrationv1.WebhookClientConfig{CABundle: []byte(caBundle)},
+			})
+		}
+		return result
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		newWebhookConfiguration(consts.WebhookName, true, "old", "old"),
+		newWebhookConfiguration(consts.PolicyWebhookName, true, "new"),
+		newWebhookConfiguration("other", false, "other"),
+	).Build()
+
+	if err := patchCABundles(context.Background(), c, []byte("new")); err != nil {
+		t.Fatalf("✗ failed to patch CA bundles: %v", err)
+	}
+
+	testdata := []struct {
+		name     string
+		caBundle string
+	}{
+		{name: consts.WebhookName, caBundle: "new"},
+		{name: consts.PolicyWebhookName, caBundle: "new"},
+		{name: "other", caBundle: "other"},
+	}
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			webhook := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+			if err := c.Get(context.Background(), client.ObjectKey{Name: tt.name}, webhook); err != nil {
+				t.Fatalf("✗ failed to get webhook: %v", err)
+			}
+			for _, w := range webhook.Webhooks {
+				if !bytes.Equal(w.ClientConfig.CABundle, []byte(tt.caBundle)) {
+					t.Errorf("✗ expected CA bundle %s, got %s", tt.caBundle, w.ClientConfig.CABundle)
+				}
+			}
+		})
+	}
+}
+
+func TestApplyWebhookWithCertManager(t *testing.T) {
+	injected := constructPolicyWebhook([]byte("injected"))
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(injected).Build()
+	r := &RecyclePolicyReconciler{
+		Client:   c,
+		CASource: certs.CASource{Kind: certs.CASourceCertManager, Namespace: consts.WebhookNamespace, Name: consts.WebhookName},
+	}
+
+	caBundle, err := r.getCABundle(context.Background())
+	if err != nil || caBundle != nil {
+		t.Fatalf("✗ expected no CA bundle with cert-manager, got %s, %v", caBundle, err)
+	}
+	if err := r.applyWebhook(context.Background(), constructPolicyWebhook(caBundle)); err != nil {
+		t.Fatalf("✗ failed to apply webhook: %v", err)
+	}
+
+	webhook := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: consts.PolicyWebhookName}, webhook); err != nil {
+		t.Fatalf("✗ failed to get webhook: %v", err)
+	}
+	if injectCAFrom := webhook.Annotations[certs.InjectCAFromAnnotation]; injectCAFrom != "krb-system/krb-webhook" {
+		t.Errorf("✗ expected inject-ca-from annotation krb-system/krb-webhook, got %q", injectCAFrom)
+	}
+	if caBundle := webhook.Webhooks[0].ClientConfig.CABundle; string(caBundle) != "injected" {
+		t.Errorf("✗ expected injected CA bundle to be kept, got %q", caBundle)
+	}
+}
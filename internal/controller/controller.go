@@ -17,6 +17,7 @@ limitations under the License.
 package controller
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"time"
@@ -27,13 +28,20 @@ import (
 
 	"github.com/go-logr/logr"
 	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/certs"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
 	"github.com/ketches/kube-recycle-bin/pkg/util"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
@@ -57,6 +65,14 @@ func Run() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var defaultRetention time.Duration
+	var defaultFailurePolicy string
+	var defaultTimeoutSeconds int
+	var defaultMatchPolicy string
+	var webhookWatchdogInterval time.Duration
+	var certCheckInterval time.Duration
+	var caSource string
+	certOptions := certs.DefaultOptions
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
@@ -66,6 +82,50 @@ func Run() {
 		"If set the metrics endpoint is served securely")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.DurationVar(&defaultRetention, "default-retention", time.Hour*24*30,
+		"How long RecycleItems are kept when their RecyclePolicy sets no retention. "+
+			"Zero keeps them forever.")
+	flag.StringVar(&defaultFailurePolicy, "default-failure-policy", string(admissionregistrationv1.Fail),
+		"Failure policy of the webhook when the RecyclePolicy sets none. One of: Fail|Ignore")
+	flag.IntVar(&defaultTimeoutSeconds, "default-timeout-seconds", 5,
+		"Timeout of the webhook in seconds when the RecyclePolicy sets none, between 1 and 30.")
+	flag.StringVar(&defaultMatchPolicy, "default-match-policy", string(admissionregistrationv1.Exact),
+		"Match policy of the webhook when the RecyclePolicy sets none. One of: Exact|Equivalent")
+	flag.DurationVar(&webhookWatchdogInterval, "webhook-watchdog-interval", time.Second*10,
+		"How often to check the endpoints of krb-webhook, webhooks fail open while it has no ready endpoints. "+
+			"Zero disables the watchdog.")
+	flag.StringVar(&caSource, "ca-source", certs.CASourceManaged,
+		"Where the CA bundle of the webhooks is taken from. managed issues and rotates the certificates of krb-webhook "+
+			"in the krb-webhook-tls Secret, secret:namespace/name[:key] and configmap:namespace/name[:key] read the CA bundle "+
+			"of externally provided certificates, and cert-manager:namespace/certificate leaves it to the CA injector of cert-manager.")
+	flag.DurationVar(&certCheckInterval, "cert-check-interval", time.Hour,
+		"How often to check the certificates of krb-webhook for renewal and the CA bundle of the webhooks.")
+	flag.DurationVar(&certOptions.Validity, "cert-validity", certOptions.Validity,
+		"Validity of the serving certificates issued to krb-webhook.")
+	flag.DurationVar(&certOptions.CAValidity, "ca-validity", certOptions.CAValidity,
+		"Validity of the CA signing the serving certificates of krb-webhook.")
+	flag.DurationVar(&certOptions.RenewBefore, "cert-renew-before", certOptions.RenewBefore,
+		"How long before they expire the certificates of krb-webhook are renewed.")
+	flag.Parse()
+
+	webhookDefaults := api.WebhookSettings{
+		FailurePolicy:  util.Ptr(admissionregistrationv1.FailurePolicyType(defaultFailurePolicy)),
+		TimeoutSeconds: util.Ptr(int32(defaultTimeoutSeconds)),
+		MatchPolicy:    util.Ptr(admissionregistrationv1.MatchPolicyType(defaultMatchPolicy)),
+	}
+	if err := webhookDefaults.Validate(); err != nil {
+		tlog.Fatalf("✗ invalid webhook defaults: %v", err)
+	}
+	if err := certOptions.Validate(); err != nil {
+		tlog.Fatalf("✗ invalid certificate options: %v", err)
+	}
+	webhookCASource, err := certs.ParseCASource(caSource)
+	if err != nil {
+		tlog.Fatalf("✗ %v", err)
+	}
+	if certCheckInterval <= 0 {
+		tlog.Fatalf("✗ invalid cert check interval %s, must be positive", certCheckInterval)
+	}
 
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
@@ -113,17 +173,63 @@ func Run() {
 		// Use a cache that syncs every 8 hours
 		Cache: cache.Options{
 			SyncPeriod: util.Ptr(time.Hour * 8),
+			// The watchdog only watches the endpoints of krb-webhook, which
+			// are cached instead of every EndpointSlice of the cluster.
+			ByObject: map[client.Object]cache.ByObject{
+				&discoveryv1.EndpointSlice{}: {
+					Namespaces: map[string]cache.Config{consts.WebhookNamespace: {}},
+					Label:      labels.SelectorFromSet(labels.Set{discoveryv1.LabelServiceName: consts.WebhookName}),
+				},
+			},
 		},
 	})
 	if err != nil {
 		tlog.Fatalf("✗ failed to start manager: %v", err)
 	}
 
-	if err = (&RecyclePolicyReconciler{
+	exclusions, err := api.LoadExclusions(context.Background(), kube.Client())
+	if err != nil {
+		tlog.Fatalf("✗ failed to load exclusions: %v", err)
+	}
+	recyclePolicyReconciler := &RecyclePolicyReconciler{
+		Client:          mgr.GetClient(),
+		Scheme:          mgr.GetScheme(),
+		WebhookDefaults: webhookDefaults,
+		Exclusions:      exclusions,
+		CASource:        webhookCASource,
+	}
+	if err = recyclePolicyReconciler.SetupWithManager(mgr); err != nil {
+		tlog.Fatalf("✗ failed to setup RecyclePolicy controller: %v", err)
+	}
+	if webhookWatchdogInterval > 0 {
+		if err = mgr.Add(&WebhookWatchdog{
+			Reconciler: recyclePolicyReconciler,
+			Interval:   webhookWatchdogInterval,
+		}); err != nil {
+			tlog.Fatalf("✗ failed to setup webhook watchdog: %v", err)
+		}
+	}
+	if webhookCASource.Kind != certs.CASourceCertManager {
+		if err = mgr.Add(&CertRotator{
+			Reconciler: recyclePolicyReconciler,
+			Options:    certOptions,
+			Interval:   certCheckInterval,
+		}); err != nil {
+			tlog.Fatalf("✗ failed to setup cert rotator: %v", err)
+		}
+	}
+	if err = (&RecyclePolicyCounter{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		tlog.Fatalf("✗ failed to setup RecyclePolicy controller: %v", err)
+		tlog.Fatalf("✗ failed to setup RecyclePolicy counter: %v", err)
+	}
+	if err = (&RecycleItemGCReconciler{
+		Client:           mgr.GetClient(),
+		Scheme:           mgr.GetScheme(),
+		DefaultRetention: defaultRetention,
+	}).SetupWithManager(mgr); err != nil {
+		tlog.Fatalf("✗ failed to setup RecycleItem GC controller: %v", err)
 	}
 	//+kubebuilder:scaffold:builder
 
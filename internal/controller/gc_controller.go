@@ -0,0 +1,128 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/internal/storage"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+// RecycleItemGCReconciler deletes api.RecycleItem objects whose retention
+// period has expired, and the payloads of deleted ones from the storage
+// backend.
+type RecycleItemGCReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// DefaultRetention applies to RecycleItems without a retention stamped
+	// by their RecyclePolicy. Zero or negative keeps them forever.
+	DefaultRetention time.Duration
+}
+
+func (r *RecycleItemGCReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	recycleItem := &api.RecycleItem{}
+	if err := r.Get(ctx, req.NamespacedName, recycleItem); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !recycleItem.DeletionTimestamp.IsZero() {
+		if err := r.deletePayload(ctx, recycleItem); err != nil {
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, r.releaseOrphan(ctx, recycleItem)
+	}
+
+	expireAt, expires, err := recycleItem.ExpireAt(r.DefaultRetention)
+	if err != nil {
+		tlog.Errorf("✗ failed to get expiry of RecycleItem [%s]: %v, ignored.", req.Name, err)
+		return ctrl.Result{}, nil
+	}
+	if !expires {
+		return ctrl.Result{}, nil
+	}
+
+	if remaining := time.Until(expireAt); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	tlog.Infof("» RecycleItem [%s] expired at %s, deleting...", req.Name, expireAt.Format(time.RFC3339))
+	if err := r.Delete(ctx, recycleItem); client.IgnoreNotFound(err) != nil {
+		tlog.Errorf("✗ failed to delete expired RecycleItem [%s]: %v", req.Name, err)
+		return ctrl.Result{}, err
+	}
+	tlog.Infof("✓ expired RecycleItem [%s] deleted.", req.Name)
+	return ctrl.Result{}, nil
+}
+
+// deletePayload deletes the payload of the deleted RecycleItem from the
+// storage backend and removes the PayloadFinalizer.
+func (r *RecycleItemGCReconciler) deletePayload(ctx context.Context, recycleItem *api.RecycleItem) error {
+	if !controllerutil.ContainsFinalizer(recycleItem, api.PayloadFinalizer) {
+		return nil
+	}
+
+	if ref := recycleItem.PayloadRef(); ref != nil {
+		backend, err := storage.Open(ctx, kube.Client(), ref.Backend, consts.PayloadDeleterSecretName)
+		if err != nil {
+			tlog.Errorf("✗ failed to open %s backend for RecycleItem [%s]: %v", ref.Backend, recycleItem.Name, err)
+			return err
+		}
+		if err := backend.Delete(ctx, ref.Key); err != nil {
+			tlog.Errorf("✗ failed to delete payload of RecycleItem [%s]: %v", recycleItem.Name, err)
+			return err
+		}
+	}
+
+	controllerutil.RemoveFinalizer(recycleItem, api.PayloadFinalizer)
+	if err := r.Update(ctx, recycleItem); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	tlog.Infof("✓ payload of RecycleItem [%s] deleted.", recycleItem.Name)
+	return nil
+}
+
+// releaseOrphan removes the CountFinalizer from the deleted RecycleItem if
+// its RecyclePolicy is gone, which would otherwise keep it forever.
+func (r *RecycleItemGCReconciler) releaseOrphan(ctx context.Context, recycleItem *api.RecycleItem) error {
+	policyName, ok := recycleItem.Labels[api.RecyclePolicyLabel]
+	if !ok || !controllerutil.ContainsFinalizer(recycleItem, api.CountFinalizer) {
+		return nil
+	}
+	err := r.Get(ctx, client.ObjectKey{Name: policyName}, &api.RecyclePolicy{})
+	if !k8serrors.IsNotFound(err) {
+		return err
+	}
+	return releaseRecycleItems(ctx, r.Client, []*api.RecycleItem{recycleItem})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RecycleItemGCReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&api.RecycleItem{}).
+		Named("recycleitem-gc").
+		Complete(r)
+}
@@ -18,171 +18,660 @@ package controller
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"slices"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/certs"
 	"github.com/ketches/kube-recycle-bin/internal/consts"
-	"github.com/ketches/kube-recycle-bin/internal/webhook"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
 	"github.com/ketches/kube-recycle-bin/pkg/util"
 	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 )
 
 // RecyclePolicyReconciler reconciles a api.RecyclePolicy object
 type RecyclePolicyReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// WebhookDefaults apply to policies that don't override them.
+	WebhookDefaults api.WebhookSettings
+	// Exclusions are never intercepted, whatever the policies target.
+	Exclusions api.Exclusions
+	// failOpen is set by the watchdog while the webhook has no ready
+	// endpoints, switching every webhook to the Ignore failure policy.
+	failOpen atomic.Bool
+	// CASource is where the CA bundle of the webhooks is taken from.
+	CASource certs.CASource
+	// caBundle is set by the cert rotator to the CA bundle of krb-webhook,
+	// or loaded from the CA source when it is nil.
+	caBundle atomic.Pointer[[]byte]
+	// rebuilds queue rebuilds of the webhook configuration requested by the
+	// watchdog and the startup sweep, so they are serialized with the
+	// reconciles of RecyclePolicies instead of racing them.
+	rebuilds chan event.GenericEvent
 }
 
+// Reconcile rebuilds the aggregated webhook configuration from all
+// RecyclePolicies, whichever of them changed.
 func (r *RecyclePolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	tlog.Infof("» reconciling RecyclePolicy [%s]...", req.Name)
-	recyclePolicy := &api.RecyclePolicy{}
-	if err := r.Get(ctx, req.NamespacedName, recyclePolicy); err != nil {
-		if k8serrors.IsNotFound(err) {
-			tlog.Infof("» watched RecyclePolicy [%s] deleted, reclaiming webhook...", req.Name)
-			if err := r.tryReclaimWebhook(ctx, req.Name); err != nil {
-				tlog.Errorf("✗ failed to reclaim webhook: %v", err)
-				return ctrl.Result{RequeueAfter: time.Second * 10}, err
+
+	recyclePolicies := &api.RecyclePolicyList{}
+	if err := r.List(ctx, recyclePolicies); err != nil {
+		tlog.Errorf("✗ failed to list recycle policies: %v", err)
+		return ctrl.Result{}, err
+	}
+
+	var active, deleting []api.RecyclePolicy
+	for _, recyclePolicy := range recyclePolicies.Items {
+		if recyclePolicy.DeletionTimestamp.IsZero() {
+			active = append(active, recyclePolicy)
+		} else {
+			deleting = append(deleting, recyclePolicy)
+		}
+	}
+
+	// The finalizer goes first, so a policy can't be deleted before its
+	// rules are removed from the webhook configuration.
+	for i := range active {
+		if controllerutil.AddFinalizer(&active[i], api.RecyclePolicyFinalizer) {
+			if err := r.Update(ctx, &active[i]); err != nil {
+				tlog.Errorf("✗ failed to add finalizer to RecyclePolicy [%s]: %v", active[i].Name, err)
+				return ctrl.Result{}, err
 			}
-			tlog.Infof("✓ webhook reclaimed for RecyclePolicy [%s] done.", req.Name)
-			return ctrl.Result{}, nil
 		}
+	}
 
-		tlog.Errorf("✗ failed to get RecyclePolicy [%s]: %v", req.Name, err)
-		return ctrl.Result{}, client.IgnoreNotFound(err)
+	if err := r.tryBuildPolicyWebhook(ctx); err != nil {
+		// RecyclePolicies are validated by krb-controller as well, recycling goes on
+		tlog.Errorf("✗ failed to build RecyclePolicy validation webhook: %v", err)
+	}
+	// The webhook configuration is left as it is if discovery fails, so the
+	// resources of "*" resources and categories aren't dropped from it.
+	discovered, webhookErr := r.discoverResources(active)
+	if webhookErr == nil {
+		webhookErr = r.tryBuildWebhook(ctx, active, discovered)
+	}
+	allResolved := true
+	for i := range active {
+		resolved, err := r.updateStatus(ctx, &active[i], discovered, webhookErr)
+		if err != nil {
+			tlog.Errorf("✗ failed to update status of RecyclePolicy [%s]: %v", active[i].Name, err)
+		}
+		allResolved = allResolved && resolved
+	}
+	if webhookErr != nil {
+		tlog.Errorf("✗ failed to build webhook for %d recycle policies: %v", len(active), webhookErr)
+		return ctrl.Result{RequeueAfter: time.Second * 10}, webhookErr
 	}
 
-	if err := r.tryBuildWebhook(ctx, recyclePolicy); err != nil {
-		tlog.Errorf("✗ failed to build webhook for RecyclePolicy [%s]: %v", req.Name, err)
-		return ctrl.Result{}, err
+	for i := range deleting {
+		if err := r.finalize(ctx, &deleting[i]); err != nil {
+			tlog.Errorf("✗ failed to finalize RecyclePolicy [%s]: %v", deleting[i].Name, err)
+			return ctrl.Result{RequeueAfter: time.Second * 10}, err
+		}
+	}
+	if err := r.tryReclaimLegacyWebhooks(ctx); err != nil {
+		tlog.Errorf("✗ failed to reclaim legacy webhooks: %v", err)
+		return ctrl.Result{RequeueAfter: time.Second * 10}, err
 	}
 
-	tlog.Infof("✓ webhook built for RecyclePolicy [%s] done.", req.Name)
+	tlog.Infof("✓ webhook built for %d recycle policies done.", len(active))
+	if !allResolved {
+		// target resources may be served later, such as after installing CRDs
+		return ctrl.Result{RequeueAfter: time.Minute}, nil
+	}
+	if discovered != nil {
+		// "*" resources and categories may cover resources served later
+		return ctrl.Result{RequeueAfter: time.Minute * 5}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
-func (r *RecyclePolicyReconciler) tryReclaimWebhook(ctx context.Context, recyclePolicyName string) error {
-	return r.Client.Delete(ctx, &admissionregistrationv1.ValidatingWebhookConfiguration{
-		ObjectMeta: metav1.ObjectMeta{
-			Name: webhookName(recyclePolicyName),
-		},
-	})
+// discoverResources returns the resources served by the cluster if any of
+// the RecyclePolicies has a "*" resource or a category, and nil otherwise.
+func (r *RecyclePolicyReconciler) discoverResources(recyclePolicies []api.RecyclePolicy) ([]metav1.APIResource, error) {
+	if !slices.ContainsFunc(recyclePolicies, func(policy api.RecyclePolicy) bool {
+		return policy.Target.IsWildcard()
+	}) {
+		return nil, nil
+	}
+
+	discovered, err := kube.GetPreferredAPIResources()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover resources: %w", err)
+	}
+	return discovered, nil
 }
 
-func (r *RecyclePolicyReconciler) tryBuildWebhook(ctx context.Context, recyclePolicy *api.RecyclePolicy) error {
-	recyclePolicies := &api.RecyclePolicyList{}
-	if err := r.List(ctx, recyclePolicies); err != nil {
-		tlog.Errorf("✗ failed to list recycle policies: %v", err)
-		return err
+// finalize purges the RecycleItems of the deleted RecyclePolicy if its
+// deletion policy says so, releases them from counting, and then removes its
+// finalizer. The policy must be out of the webhook configuration already.
+func (r *RecyclePolicyReconciler) finalize(ctx context.Context, recyclePolicy *api.RecyclePolicy) error {
+	if !controllerutil.ContainsFinalizer(recyclePolicy, api.RecyclePolicyFinalizer) {
+		return nil
 	}
 
-	webhook := constructWebhookFromPolicy(recyclePolicy)
-	currentWebhook := &admissionregistrationv1.ValidatingWebhookConfiguration{}
-	if err := r.Get(ctx, types.NamespacedName{Name: webhook.Name}, currentWebhook); err != nil {
-		if k8serrors.IsNotFound(err) {
-			tlog.Infof("» creating webhook for RecyclePolicy [%s]...", recyclePolicy.Name)
-			if err := r.Client.Create(ctx, webhook); err != nil {
-				tlog.Errorf("✗ failed to create webhook for RecyclePolicy [%s]: %v", recyclePolicy.Name, err)
+	recycleItems := &api.RecycleItemList{}
+	if err := r.List(ctx, recycleItems, client.MatchingLabels{api.RecyclePolicyLabel: recyclePolicy.Name}); err != nil {
+		return err
+	}
+	if recyclePolicy.DeletionPolicy == api.DeletionPolicyPurge {
+		for i := range recycleItems.Items {
+			if err := r.Delete(ctx, &recycleItems.Items[i]); client.IgnoreNotFound(err) != nil {
 				return err
 			}
-			tlog.Infof("✓ webhook for RecyclePolicy [%s] created.", recyclePolicy.Name)
-			return nil
 		}
+		tlog.Infof("✓ purged %d RecycleItems of deleted RecyclePolicy [%s].", len(recycleItems.Items), recyclePolicy.Name)
+	}
+	released := make([]*api.RecycleItem, len(recycleItems.Items))
+	for i := range recycleItems.Items {
+		released[i] = &recycleItems.Items[i]
+	}
+	if err := releaseRecycleItems(ctx, r.Client, released); err != nil {
 		return err
 	}
 
-	// update the webhook
-	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
-		webhook.SetResourceVersion(currentWebhook.ResourceVersion)
+	controllerutil.RemoveFinalizer(recyclePolicy, api.RecyclePolicyFinalizer)
+	if err := r.Update(ctx, recyclePolicy); client.IgnoreNotFound(err) != nil {
+		return err
+	}
+	tlog.Infof("✓ RecyclePolicy [%s] finalized.", recyclePolicy.Name)
+	return nil
+}
+
+// sweep runs once on startup. It deletes the legacy webhook configurations
+// whose RecyclePolicy no longer exists, and rebuilds the webhook configuration
+// in case policies were deleted while krb-controller was down.
+func (r *RecyclePolicyReconciler) sweep(ctx context.Context) error {
+	tlog.Infof("» sweeping orphaned webhooks...")
+
+	legacyWebhooks := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := r.List(ctx, legacyWebhooks, client.HasLabels{api.RecyclePolicyLabel}); err != nil {
+		tlog.Errorf("✗ failed to list legacy webhooks: %v", err)
+		return nil
+	}
+	for i := range legacyWebhooks.Items {
+		policyName := legacyWebhooks.Items[i].Labels[api.RecyclePolicyLabel]
+		err := r.Get(ctx, types.NamespacedName{Name: policyName}, &api.RecyclePolicy{})
+		if !k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err := r.Delete(ctx, &legacyWebhooks.Items[i]); client.IgnoreNotFound(err) != nil {
+			tlog.Errorf("✗ failed to delete orphaned webhook [%s]: %v", legacyWebhooks.Items[i].Name, err)
+			continue
+		}
+		tlog.Infof("✓ orphaned webhook [%s] of RecyclePolicy [%s] deleted.", legacyWebhooks.Items[i].Name, policyName)
+	}
+
+	r.requestRebuild()
+	return nil
+}
+
+// requestRebuild queues a rebuild of the webhook configuration, unless one is
+// queued already, which reads the current state when it runs.
+func (r *RecyclePolicyReconciler) requestRebuild() {
+	select {
+	case r.rebuilds <- event.GenericEvent{Object: &api.RecyclePolicy{}}:
+	default:
+	}
+}
+
+// updateStatus updates the conditions of the RecyclePolicy, leaving the
+// counters maintained by the RecyclePolicyCounter untouched. It reports whether
+// the target resources are resolved, an excluded target never will be.
+func (r *RecyclePolicyReconciler) updateStatus(ctx context.Context, recyclePolicy *api.RecyclePolicy, discovered []metav1.APIResource, webhookErr error) (bool, error) {
+	target := &recyclePolicy.Target
+	targetErr := r.Exclusions.Validate(target)
+	if targetErr == nil {
+		targetErr = r.resolveTarget(target, discovered)
+	}
+	resolved := targetErr == nil || errors.Is(targetErr, api.ErrExcluded)
+
+	var desired api.RecyclePolicyStatus
+	recyclePolicy.Status.DeepCopyInto(&desired)
+	setPolicyConditions(&desired, recyclePolicy.Generation, webhookErr, targetErr)
+	desired.Resources = nil
+	if targetErr == nil {
+		for _, gr := range target.Resolve(discovered, &r.Exclusions) {
+			desired.Resources = append(desired.Resources, gr.String())
+		}
+	}
+	if equality.Semantic.DeepEqual(desired, recyclePolicy.Status) {
+		return resolved, nil
+	}
+
+	return resolved, retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		latest := &api.RecyclePolicy{}
+		if err := r.Get(ctx, types.NamespacedName{Name: recyclePolicy.Name}, latest); err != nil {
+			return client.IgnoreNotFound(err)
+		}
+		latest.Status.ObservedGeneration = desired.ObservedGeneration
+		latest.Status.WebhookConfiguration = desired.WebhookConfiguration
+		latest.Status.Conditions = desired.Conditions
+		latest.Status.Resources = desired.Resources
+		return r.Status().Update(ctx, latest)
+	})
+}
+
+// resolveTarget returns an error if a resource listed by the target isn't
+// served by the cluster, or a "*" resource or the category of the target
+// doesn't cover any of the discovered resources.
+func (r *RecyclePolicyReconciler) resolveTarget(target *api.RecycleTarget, discovered []metav1.APIResource) error {
+	for _, tr := range target.TargetResources() {
+		if tr.IsWildcard() {
+			wildcard := api.RecycleTarget{Group: tr.Group, Resource: tr.Resource, Namespaces: target.Namespaces, NamespaceSelector: target.NamespaceSelector}
+			if len(wildcard.Resolve(discovered, &r.Exclusions)) == 0 {
+				return fmt.Errorf("no resources found for %s", tr.GroupResource().String())
+			}
+			continue
+		}
+		if _, err := r.RESTMapper().KindFor(schema.GroupVersionResource{Group: tr.Group, Resource: tr.Resource}); err != nil {
+			return err
+		}
+	}
+	if target.Category != "" {
+		category := api.RecycleTarget{Category: target.Category, Namespaces: target.Namespaces, NamespaceSelector: target.NamespaceSelector}
+		if len(category.Resolve(discovered, &r.Exclusions)) == 0 {
+			return fmt.Errorf("no resources found in category %s", target.Category)
+		}
+	}
+	return nil
+}
+
+// setPolicyConditions sets the conditions of the RecyclePolicy status from
+// the errors of building the webhook and resolving the target.
+func setPolicyConditions(status *api.RecyclePolicyStatus, generation int64, webhookErr, targetErr error) {
+	status.ObservedGeneration = generation
+	status.WebhookConfiguration = ""
+
+	webhookConfigured := metav1.Condition{
+		Type:               api.RecyclePolicyConditionWebhookConfigured,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Configured",
+		Message:            "webhook configuration " + consts.WebhookName + " is up to date",
+		ObservedGeneration: generation,
+	}
+	if webhookErr != nil {
+		webhookConfigured.Status = metav1.ConditionFalse
+		webhookConfigured.Reason = "ConfigureFailed"
+		webhookConfigured.Message = webhookErr.Error()
+	} else {
+		status.WebhookConfiguration = consts.WebhookName
+	}
+
+	targetResolved := metav1.Condition{
+		Type:               api.RecyclePolicyConditionTargetResolved,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Resolved",
+		Message:            "target resource is served by the cluster",
+		ObservedGeneration: generation,
+	}
+	if targetErr != nil {
+		targetResolved.Status = metav1.ConditionFalse
+		targetResolved.Reason = util.If(errors.Is(targetErr, api.ErrExcluded), "Excluded", "NotFound")
+		targetResolved.Message = targetErr.Error()
+	}
 
-		if err := r.Client.Update(ctx, webhook); err != nil {
-			// update failed, try to get the latest version, and retry or return error
-			if err := r.Client.Get(ctx, types.NamespacedName{Name: webhook.Name}, currentWebhook); err != nil {
+	ready := metav1.Condition{
+		Type:               api.RecyclePolicyConditionReady,
+		Status:             metav1.ConditionTrue,
+		Reason:             "Ready",
+		Message:            "deleted objects of the target are recycled",
+		ObservedGeneration: generation,
+	}
+	for _, condition := range []metav1.Condition{webhookConfigured, targetResolved} {
+		if condition.Status != metav1.ConditionTrue {
+			ready.Status = metav1.ConditionFalse
+			ready.Reason = condition.Reason
+			ready.Message = condition.Message
+			break
+		}
+	}
+
+	meta.SetStatusCondition(&status.Conditions, webhookConfigured)
+	meta.SetStatusCondition(&status.Conditions, targetResolved)
+	meta.SetStatusCondition(&status.Conditions, ready)
+}
+
+// tryReclaimLegacyWebhooks deletes the webhook configurations created per
+// RecyclePolicy by earlier versions.
+func (r *RecyclePolicyReconciler) tryReclaimLegacyWebhooks(ctx context.Context) error {
+	legacyWebhooks := &admissionregistrationv1.ValidatingWebhookConfigurationList{}
+	if err := r.List(ctx, legacyWebhooks, client.HasLabels{api.RecyclePolicyLabel}); err != nil {
+		return err
+	}
+	for i := range legacyWebhooks.Items {
+		if err := r.Client.Delete(ctx, &legacyWebhooks.Items[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		tlog.Infof("✓ legacy webhook [%s] reclaimed.", legacyWebhooks.Items[i].Name)
+	}
+	return nil
+}
+
+// tryBuildWebhook creates or updates the aggregated webhook configuration, and
+// deletes it when there are no RecyclePolicies left.
+func (r *RecyclePolicyReconciler) tryBuildWebhook(ctx context.Context, recyclePolicies []api.RecyclePolicy, discovered []metav1.APIResource) error {
+	caBundle, err := r.getCABundle(ctx)
+	if err != nil {
+		return err
+	}
+	webhook := constructWebhookFromPolicies(recyclePolicies, webhookOptions{
+		CABundle:   caBundle,
+		Discovered: discovered,
+		Defaults:   r.WebhookDefaults,
+		Exclusions: r.Exclusions,
+		FailOpen:   r.failOpen.Load(),
+	})
+	if len(webhook.Webhooks) == 0 {
+		tlog.Infof("» no recycle policies left, reclaiming webhook...")
+		return client.IgnoreNotFound(r.Client.Delete(ctx, webhook))
+	}
+
+	return r.applyWebhook(ctx, webhook)
+}
+
+// tryBuildPolicyWebhook creates or updates the webhook configuration that
+// validates RecyclePolicies, which exists whether or not there are any.
+func (r *RecyclePolicyReconciler) tryBuildPolicyWebhook(ctx context.Context) error {
+	caBundle, err := r.getCABundle(ctx)
+	if err != nil {
+		return err
+	}
+	return r.applyWebhook(ctx, constructPolicyWebhook(caBundle))
+}
+
+// applyWebhook creates the webhook configuration or updates the existing one.
+func (r *RecyclePolicyReconciler) applyWebhook(ctx context.Context, webhook *admissionregistrationv1.ValidatingWebhookConfiguration) error {
+	certManager := r.CASource.Kind == certs.CASourceCertManager
+	if certManager {
+		metav1.SetMetaDataAnnotation(&webhook.ObjectMeta, certs.InjectCAFromAnnotation, r.CASource.Namespace+"/"+r.CASource.Name)
+	}
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		currentWebhook := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Get(ctx, types.NamespacedName{Name: webhook.Name}, currentWebhook); err != nil {
+			if !k8serrors.IsNotFound(err) {
 				return err
 			}
+			tlog.Infof("» creating webhook [%s]...", webhook.Name)
+			return r.Client.Create(ctx, webhook)
 		}
-		tlog.Infof("✓ webhook for RecyclePolicy [%s] updated.", recyclePolicy.Name)
-		return nil
+
+		if certManager {
+			keepInjectedCABundle(webhook, currentWebhook)
+		}
+		webhook.SetResourceVersion(currentWebhook.ResourceVersion)
+		return r.Client.Update(ctx, webhook)
 	})
 }
 
-func constructWebhookFromPolicy(recyclePolicy *api.RecyclePolicy) *admissionregistrationv1.ValidatingWebhookConfiguration {
+// keepInjectedCABundle keeps the CA bundle the CA injector of cert-manager
+// injected into the current webhooks, which would otherwise be cleared until
+// it injects it again.
+func keepInjectedCABundle(webhook, current *admissionregistrationv1.ValidatingWebhookConfiguration) {
+	var caBundle []byte
+	for _, w := range current.Webhooks {
+		if len(w.ClientConfig.CABundle) > 0 {
+			caBundle = w.ClientConfig.CABundle
+			break
+		}
+	}
+	for i := range webhook.Webhooks {
+		webhook.Webhooks[i].ClientConfig.CABundle = caBundle
+	}
+}
+
+// webhookOptions are the cluster-wide options of the webhook configuration.
+type webhookOptions struct {
+	CABundle []byte
+	Defaults api.WebhookSettings
+	// Discovered are the resources served by the cluster, which "*" resources
+	// and categories of the targets are resolved to.
+	Discovered []metav1.APIResource
+	// Exclusions are left out of the webhook configuration.
+	Exclusions api.Exclusions
+	// FailOpen switches every webhook to the Ignore failure policy.
+	FailOpen bool
+}
+
+// constructWebhookFromPolicies builds a single webhook configuration for all
+// RecyclePolicies. Policies with identical namespace and object selectors and
+// webhook settings share a webhook, so the API server calls the webhook once
+// per deletion. Deletions matching the webhooks of several policies are only
+// recycled by the first webhook called. Policies targeting excluded resources or namespaces are
+// skipped, and excluded namespaces are left out of the namespace selectors.
+// "*" resources and categories are resolved to the discovered resources.
+func constructWebhookFromPolicies(recyclePolicies []api.RecyclePolicy, opts webhookOptions) *admissionregistrationv1.ValidatingWebhookConfiguration {
 	result := &admissionregistrationv1.ValidatingWebhookConfiguration{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: webhookName(recyclePolicy.Name),
+			Name: consts.WebhookName,
 			Labels: map[string]string{
-				"krb.ketches.cn/recycle-policy": recyclePolicy.Name,
+				consts.ManagedByLabel: consts.ControllerName,
 			},
 		},
-		Webhooks: []admissionregistrationv1.ValidatingWebhook{
-			{
-				AdmissionReviewVersions: []string{"v1"},
-				ClientConfig: admissionregistrationv1.WebhookClientConfig{
-					CABundle: getCertBytes(),
-					Service: &admissionregistrationv1.ServiceReference{
-						Name:      consts.WebhookName,
-						Namespace: consts.WebhookNamespace,
-						Path:      util.Ptr(consts.WebhookServicePath),
-					},
+	}
+
+	recyclePolicies = slices.Clone(recyclePolicies)
+	slices.SortFunc(recyclePolicies, func(a, b api.RecyclePolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	// webhooks are keyed by their selectors and settings, resources by their group
+	webhookIndex := map[string]int{}
+	resourcesByGroup := map[int]map[string][]string{}
+	for i := range recyclePolicies {
+		target := &recyclePolicies[i].Target
+		if err := opts.Exclusions.Validate(target); err != nil {
+			tlog.Warnf("» skip RecyclePolicy [%s]: %v", recyclePolicies[i].Name, err)
+			continue
+		}
+		groupResources := target.Resolve(opts.Discovered, &opts.Exclusions)
+		if len(groupResources) == 0 {
+			continue
+		}
+		namespaceSelector := namespaceSelectorFor(target, opts.Exclusions.Namespaces)
+		settings := recyclePolicies[i].Webhook.WithDefaults(opts.Defaults)
+		if opts.FailOpen {
+			settings.FailurePolicy = util.Ptr(admissionregistrationv1.Ignore)
+		}
+		key := webhookKey(namespaceSelector, target.ObjectSelector, settings)
+
+		index, ok := webhookIndex[key]
+		if !ok {
+			index = len(result.Webhooks)
+			webhookIndex[key] = index
+			resourcesByGroup[index] = map[string][]string{}
+			webhook := newValidatingWebhook(fmt.Sprintf("%d.%s", index, consts.WebhookDNSName), consts.WebhookServicePath, opts.CABundle, settings)
+			webhook.NamespaceSelector = namespaceSelector
+			if target.ObjectSelector != nil {
+				webhook.ObjectSelector = target.ObjectSelector.DeepCopy()
+			}
+			result.Webhooks = append(result.Webhooks, webhook)
+		}
+		for _, gr := range groupResources {
+			if !slices.Contains(resourcesByGroup[index][gr.Group], gr.Resource) {
+				resourcesByGroup[index][gr.Group] = append(resourcesByGroup[index][gr.Group], gr.Resource)
+			}
+		}
+	}
+
+	for index := range result.Webhooks {
+		for _, group := range slices.Sorted(maps.Keys(resourcesByGroup[index])) {
+			resources := resourcesByGroup[index][group]
+			slices.Sort(resources)
+			result.Webhooks[index].Rules = append(result.Webhooks[index].Rules, admissionregistrationv1.RuleWithOperations{
+				Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
+				Rule: admissionregistrationv1.Rule{
+					APIGroups:   []string{group},
+					APIVersions: []string{"*"},
+					Resources:   resources,
 				},
-				FailurePolicy:  util.Ptr(admissionregistrationv1.Fail),
-				MatchPolicy:    util.Ptr(admissionregistrationv1.Exact),
-				Name:           consts.WebhookDNSName,
-				SideEffects:    util.Ptr(admissionregistrationv1.SideEffectClassNone),
-				TimeoutSeconds: util.Ptr(int32(5)),
+			})
+		}
+	}
+	return result
+}
+
+// constructPolicyWebhook builds the webhook configuration validating created
+// and updated RecyclePolicies. It ignores failures, so krb-controller can
+// still finalize RecyclePolicies while krb-webhook is down.
+func constructPolicyWebhook(caBundle []byte) *admissionregistrationv1.ValidatingWebhookConfiguration {
+	webhook := newValidatingWebhook("recyclepolicies."+consts.WebhookDNSName, consts.WebhookPolicyPath, caBundle, api.WebhookSettings{
+		FailurePolicy:  util.Ptr(admissionregistrationv1.Ignore),
+		TimeoutSeconds: util.Ptr(int32(10)),
+		MatchPolicy:    util.Ptr(admissionregistrationv1.Equivalent),
+	})
+	webhook.SideEffects = util.Ptr(admissionregistrationv1.SideEffectClassNone)
+	webhook.Rules = []admissionregistrationv1.RuleWithOperations{
+		{
+			Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Create, admissionregistrationv1.Update},
+			Rule: admissionregistrationv1.Rule{
+				APIGroups:   []string{api.Group},
+				APIVersions: []string{"*"},
+				Resources:   []string{"recyclepolicies"},
 			},
 		},
 	}
 
-	result.Webhooks[0].Rules = append(result.Webhooks[0].Rules, admissionregistrationv1.RuleWithOperations{
-		Operations: []admissionregistrationv1.OperationType{admissionregistrationv1.Delete},
-		Rule: admissionregistrationv1.Rule{
-			APIGroups:   []string{recyclePolicy.Target.Group},
-			APIVersions: []string{"*"},
-			Resources:   []string{recyclePolicy.Target.Resource},
+	return &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: consts.PolicyWebhookName,
+			Labels: map[string]string{
+				consts.ManagedByLabel: consts.ControllerName,
+			},
 		},
-	})
+		Webhooks: []admissionregistrationv1.ValidatingWebhook{webhook},
+	}
+}
+
+func newValidatingWebhook(name, path string, caBundle []byte, settings api.WebhookSettings) admissionregistrationv1.ValidatingWebhook {
+	return admissionregistrationv1.ValidatingWebhook{
+		AdmissionReviewVersions: []string{"v1"},
+		ClientConfig: admissionregistrationv1.WebhookClientConfig{
+			CABundle: caBundle,
+			Service: &admissionregistrationv1.ServiceReference{
+				Name:      consts.WebhookName,
+				Namespace: consts.WebhookNamespace,
+				Path:      util.Ptr(path),
+			},
+		},
+		FailurePolicy: settings.FailurePolicy,
+		MatchPolicy:   settings.MatchPolicy,
+		Name:          name,
+		// Recycling creates RecycleItems, which the webhook skips for dry-run requests.
+		SideEffects:    util.Ptr(admissionregistrationv1.SideEffectClassNoneOnDryRun),
+		TimeoutSeconds: settings.TimeoutSeconds,
+	}
+}
+
+// webhookKey identifies the namespace and object selectors and settings of a
+// webhook.
+func webhookKey(namespaceSelector, objectSelector *metav1.LabelSelector, settings api.WebhookSettings) string {
+	b, _ := json.Marshal([]any{namespaceSelector, objectSelector, settings})
+	return string(b)
+}
 
+// namespaceSelectorFor merges the namespace list and the namespace selector
+// of the target into a single webhook namespace selector. A target in all
+// namespaces matches all but the excluded namespaces.
+func namespaceSelectorFor(target *api.RecycleTarget, excludedNamespaces []string) *metav1.LabelSelector {
 	var namespaceSelector metav1.LabelSelectorRequirement
-	if len(recyclePolicy.Target.Namespaces) == 0 || slices.Contains(recyclePolicy.Target.Namespaces, metav1.NamespaceAll) || slices.Contains(recyclePolicy.Target.Namespaces, "*") {
+	if len(target.Namespaces) == 0 || slices.Contains(target.Namespaces, metav1.NamespaceAll) || slices.Contains(target.Namespaces, "*") {
 		namespaceSelector = metav1.LabelSelectorRequirement{
-			Key:      "kubernetes.io/metadata.name",
+			Key:      corev1.LabelMetadataName,
 			Operator: metav1.LabelSelectorOpExists, // match all namespaces
 		}
+		if len(excludedNamespaces) > 0 {
+			namespaceSelector.Operator = metav1.LabelSelectorOpNotIn
+			namespaceSelector.Values = slices.Sorted(slices.Values(excludedNamespaces))
+		}
 	} else {
 		namespaceSelector = metav1.LabelSelectorRequirement{
-			Key:      "kubernetes.io/metadata.name",
+			Key:      corev1.LabelMetadataName,
 			Operator: metav1.LabelSelectorOpIn,
-			Values:   recyclePolicy.Target.Namespaces,
+			Values:   slices.Sorted(slices.Values(target.Namespaces)),
 		}
 	}
 
-	result.Webhooks[0].NamespaceSelector = &metav1.LabelSelector{
-		MatchExpressions: []metav1.LabelSelectorRequirement{namespaceSelector},
+	result := &metav1.LabelSelector{}
+	if target.NamespaceSelector != nil {
+		result = target.NamespaceSelector.DeepCopy()
 	}
+	result.MatchExpressions = append(result.MatchExpressions, namespaceSelector)
 	return result
 }
 
-func webhookName(policyName string) string {
-	return consts.WebhookName + "-" + policyName
-}
+// getCABundle returns the CA bundle of krb-webhook from the CA source,
+// creating the krb-webhook-tls Secret if it is managed and there is none yet.
+// There is none if cert-manager injects it.
+func (r *RecyclePolicyReconciler) getCABundle(ctx context.Context) ([]byte, error) {
+	if caBundle := r.caBundle.Load(); caBundle != nil {
+		return *caBundle, nil
+	}
 
-func getCertBytes() []byte {
-	cert, _ := webhook.FetchWebhookCertAndKey()
-	return cert
+	var caBundle []byte
+	switch r.CASource.Kind {
+	case certs.CASourceCertManager:
+		return nil, nil
+	case certs.CASourceSecret, certs.CASourceConfigMap:
+		var err error
+		if caBundle, err = certs.LoadCABundle(ctx, kube.Client(), r.CASource); err != nil {
+			return nil, err
+		}
+	default:
+		bundle, _, err := certs.Ensure(ctx, kube.Client(), certs.DefaultOptions, false)
+		if err != nil {
+			return nil, err
+		}
+		caBundle = bundle.CABundle
+	}
+	r.caBundle.Store(&caBundle)
+	return caBundle, nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller and the startup sweep with the Manager.
 func (r *RecyclePolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.rebuilds = make(chan event.GenericEvent, 1)
+	if err := mgr.Add(manager.RunnableFunc(r.sweep)); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&api.RecyclePolicy{}).
+		// status updates by the controller itself and the counter
+		// don't change the webhook configuration
+		For(&api.RecyclePolicy{}, builder.WithPredicates(predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.NewPredicateFuncs(func(obj client.Object) bool {
+				return !obj.GetDeletionTimestamp().IsZero()
+			}),
+		))).
+		WatchesRawSource(source.Channel(r.rebuilds, &handler.EnqueueRequestForObject{})).
 		Complete(r)
 }
@@ -0,0 +1,247 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceSelectorFor(t *testing.T) {
+	allNamespaces := metav1.LabelSelectorRequirement{
+		Key:      "kubernetes.io/metadata.name",
+		Operator: metav1.LabelSelectorOpExists,
+	}
+
+	testdata := []struct {
+		name     string
+		target   api.RecycleTarget
+		excluded []string
+		desired  *metav1.LabelSelector
+	}{
+		{
+			name:   "all-namespaces",
+			target: api.RecycleTarget{Resource: "deployments"},
+			desired: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{allNamespaces},
+			},
+		},
+		{
+			name:     "all-but-excluded-namespaces",
+			target:   api.RecycleTarget{Resource: "deployments"},
+			excluded: []string{"kube-system", "krb-system"},
+			desired: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "kubernetes.io/metadata.name",
+						Operator: metav1.LabelSelectorOpNotIn,
+						Values:   []string{"krb-system", "kube-system"},
+					},
+				},
+			},
+		},
+		{
+			name:     "namespace-list",
+			target:   api.RecycleTarget{Resource: "deployments", Namespaces: []string{"dev", "prod"}},
+			excluded: []string{"krb-system"},
+			desired: &metav1.LabelSelector{
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "kubernetes.io/metadata.name",
+						Operator: metav1.LabelSelectorOpIn,
+						Values:   []string{"dev", "prod"},
+					},
+				},
+			},
+		},
+		{
+			name: "namespace-selector",
+			target: api.RecycleTarget{
+				Resource: "deployments",
+				NamespaceSelector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"env": "prod"},
+					MatchExpressions: []metav1.LabelSelectorRequirement{
+						{
+							Key:      "tier",
+							Operator: metav1.LabelSelectorOpNotIn,
+							Values:   []string{"sandbox"},
+						},
+					},
+				},
+			},
+			desired: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"env": "prod"},
+				MatchExpressions: []metav1.LabelSelectorRequirement{
+					{
+						Key:      "tier",
+						Operator: metav1.LabelSelectorOpNotIn,
+						Values:   []string{"sandbox"},
+					},
+					allNamespaces,
+				},
+			},
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			got := namespaceSelectorFor(&tt.target, tt.excluded)
+			if !reflect.DeepEqual(got, tt.desired) {
+				t.Errorf("✗ expected %v, got %v", tt.desired, got)
+			}
+		})
+	}
+
+	// the namespace selector of the target must not be modified
+	target := testdata[3].target
+	namespaceSelectorFor(&target, nil)
+	if len(target.NamespaceSelector.MatchExpressions) != 1 {
+		t.Errorf("✗ expected namespace selector of the target unchanged, got %v", target.NamespaceSelector)
+	}
+}
+
+func TestConstructWebhookFromPolicies(t *testing.T) {
+	newPolicy := func(name, group, resource string, namespaces ...string) api.RecyclePolicy {
+		return api.RecyclePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Target:     api.RecycleTarget{Group: group, Resource: resource, Namespaces: namespaces},
+		}
+	}
+	policies := []api.RecyclePolicy{
+		newPolicy("d", "", "configmaps", "prod", "dev"),
+		newPolicy("a", "apps", "deployments", "dev", "prod"),
+		newPolicy("b", "", "services", "dev", "prod"),
+		newPolicy("c", "apps", "statefulsets", "dev", "prod"),
+		newPolicy("e", "apps", "deployments"),
+	}
+
+	defaults := api.WebhookSettings{
+		FailurePolicy:  util.Ptr(admissionregistrationv1.Fail),
+		TimeoutSeconds: util.Ptr(int32(5)),
+		MatchPolicy:    util.Ptr(admissionregistrationv1.Exact),
+	}
+	result := constructWebhookFromPolicies(policies, webhookOptions{Defaults: defaults})
+	if result.Name != "krb-webhook" {
+		t.Errorf("✗ expected webhook configuration krb-webhook, got %s", result.Name)
+	}
+	if len(result.Webhooks) != 2 {
+		t.Fatalf("✗ expected 2 webhooks, got %d", len(result.Webhooks))
+	}
+
+	desired := []admissionregistrationv1.Rule{
+		{APIGroups: []string{""}, APIVersions: []string{"*"}, Resources: []string{"configmaps", "services"}},
+		{APIGroups: []string{"apps"}, APIVersions: []string{"*"}, Resources: []string{"deployments", "statefulsets"}},
+	}
+	var got []admissionregistrationv1.Rule
+	for _, rule := range result.Webhooks[0].Rules {
+		got = append(got, rule.Rule)
+	}
+	if !reflect.DeepEqual(got, desired) {
+		t.Errorf("✗ expected rules %v, got %v", desired, got)
+	}
+	if result.Webhooks[0].Name == result.Webhooks[1].Name {
+		t.Errorf("✗ expected unique webhook names, got %s", result.Webhooks[0].Name)
+	}
+
+	if empty := constructWebhookFromPolicies(nil, webhookOptions{Defaults: defaults}); len(empty.Webhooks) != 0 {
+		t.Errorf("✗ expected no webhooks without policies, got %d", len(empty.Webhooks))
+	}
+
+	// a policy with its own failure policy gets its own webhook
+	policies[1].Webhook = &api.WebhookSettings{FailurePolicy: util.Ptr(admissionregistrationv1.Ignore)}
+	result = constructWebhookFromPolicies(policies, webhookOptions{Defaults: defaults})
+	if len(result.Webhooks) != 3 {
+		t.Fatalf("✗ expected 3 webhooks, got %d", len(result.Webhooks))
+	}
+	if *result.Webhooks[0].FailurePolicy != admissionregistrationv1.Ignore || *result.Webhooks[0].TimeoutSeconds != 5 {
+		t.Errorf("✗ expected failure policy Ignore and default timeout, got %s and %d", *result.Webhooks[0].FailurePolicy, *result.Webhooks[0].TimeoutSeconds)
+	}
+
+	// excluded targets are left out
+	policies = append(policies, newPolicy("f", "krb.ketches.cn", "recycleitems"), newPolicy("g", "", "secrets", "krb-system"))
+	result = constructWebhookFromPolicies(policies, webhookOptions{Defaults: defaults, Exclusions: api.NewExclusions(nil, nil)})
+	if len(result.Webhooks) != 3 {
+		t.Fatalf("✗ expected 3 webhooks, got %d", len(result.Webhooks))
+	}
+	for _, webhook := range result.Webhooks {
+		for _, rule := range webhook.Rules {
+			if slices.Contains(rule.APIGroups, "krb.ketches.cn") || slices.Contains(rule.Resources, "secrets") {
+				t.Errorf("✗ expected excluded targets left out, got rule %v", rule.Rule)
+			}
+		}
+	}
+
+	// "*" resources and categories are resolved to the discovered resources
+	discovered := []metav1.APIResource{
+		{Group: "batch", Name: "jobs", Namespaced: true, Verbs: []string{"delete"}},
+		{Group: "batch", Name: "jobs/status", Namespaced: true, Verbs: []string{"update"}},
+		{Group: "batch", Name: "cronjobs", Namespaced: true, Verbs: []string{"delete"}},
+	}
+	wildcard := []api.RecyclePolicy{newPolicy("h", "batch", "*"), newPolicy("i", "example.com", "*")}
+	result = constructWebhookFromPolicies(wildcard, webhookOptions{Defaults: defaults, Discovered: discovered})
+	if len(result.Webhooks) != 1 || len(result.Webhooks[0].Rules) != 1 {
+		t.Fatalf("✗ expected 1 webhook with 1 rule, got %v", result.Webhooks)
+	}
+	if desired := []string{"cronjobs", "jobs"}; !slices.Equal(result.Webhooks[0].Rules[0].Resources, desired) {
+		t.Errorf("✗ expected resources %v, got %v", desired, result.Webhooks[0].Rules[0].Resources)
+	}
+
+	// failing open switches every webhook to Ignore
+	result = constructWebhookFromPolicies(policies, webhookOptions{Defaults: defaults, FailOpen: true})
+	for _, webhook := range result.Webhooks {
+		if *webhook.FailurePolicy != admissionregistrationv1.Ignore {
+			t.Errorf("✗ expected failure policy Ignore when failing open, got %s", *webhook.FailurePolicy)
+		}
+	}
+}
+
+func TestSetPolicyConditions(t *testing.T) {
+	status := &api.RecyclePolicyStatus{RecycledCount: 3}
+	setPolicyConditions(status, 2, nil, errors.New("no matches for foos"))
+
+	if status.ObservedGeneration != 2 || status.WebhookConfiguration != "krb-webhook" || status.RecycledCount != 3 {
+		t.Errorf("✗ unexpected status %+v", status)
+	}
+	for conditionType, desired := range map[string]metav1.ConditionStatus{
+		api.RecyclePolicyConditionWebhookConfigured: metav1.ConditionTrue,
+		api.RecyclePolicyConditionTargetResolved:    metav1.ConditionFalse,
+		api.RecyclePolicyConditionReady:             metav1.ConditionFalse,
+	} {
+		if condition := meta.FindStatusCondition(status.Conditions, conditionType); condition == nil || condition.Status != desired {
+			t.Errorf("✗ expected condition %s to be %s, got %v", conditionType, desired, condition)
+		}
+	}
+
+	setPolicyConditions(status, 3, nil, fmt.Errorf("resource leases.coordination.k8s.io is %w", api.ErrExcluded))
+	if condition := meta.FindStatusCondition(status.Conditions, api.RecyclePolicyConditionTargetResolved); condition == nil || condition.Reason != "Excluded" {
+		t.Errorf("✗ expected condition TargetResolved with reason Excluded, got %v", condition)
+	}
+
+	setPolicyConditions(status, 3, nil, nil)
+	if !meta.IsStatusConditionTrue(status.Conditions, api.RecyclePolicyConditionReady) {
+		t.Errorf("✗ expected condition Ready to be True, got %v", status.Conditions)
+	}
+}
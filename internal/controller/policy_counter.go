@@ -0,0 +1,137 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// RecyclePolicyCounter counts the objects recycled, restored and purged by
+// each api.RecyclePolicy from the RecycleItems labelled with it. It is the
+// only writer of the counters, so a mass deletion doesn't make every
+// recycled object update the status of the same policy.
+type RecyclePolicyCounter struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+func (r *RecyclePolicyCounter) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	recyclePolicy := &api.RecyclePolicy{}
+	if err := r.Get(ctx, req.NamespacedName, recyclePolicy); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if !recyclePolicy.DeletionTimestamp.IsZero() {
+		// the RecycleItems are released by the finalizer of the policy
+		return ctrl.Result{}, nil
+	}
+
+	recycleItems := &api.RecycleItemList{}
+	if err := r.List(ctx, recycleItems, client.MatchingLabels{api.RecyclePolicyLabel: recyclePolicy.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+	status, counted := countRecycleItems(recyclePolicy.Status, recycleItems.Items)
+	if status.RecycledCount != recyclePolicy.Status.RecycledCount || len(counted) > 0 {
+		recyclePolicy.Status = status
+		if err := r.Status().Update(ctx, recyclePolicy); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Deleted RecycleItems are released only after they are counted, so
+	// they are counted even if krb-controller restarts in between.
+	if err := releaseRecycleItems(ctx, r.Client, counted); err != nil {
+		tlog.Errorf("✗ failed to release counted RecycleItems of RecyclePolicy [%s]: %v", recyclePolicy.Name, err)
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// countRecycleItems returns the status with the counters updated from the
+// RecycleItems of the policy, and the deleted RecycleItems it counted as
+// restored or purged.
+func countRecycleItems(status api.RecyclePolicyStatus, recycleItems []api.RecycleItem) (api.RecyclePolicyStatus, []*api.RecycleItem) {
+	var recycled int64
+	var counted []*api.RecycleItem
+	for i := range recycleItems {
+		recycleItem := &recycleItems[i]
+		if recycleItem.DeletionTimestamp.IsZero() {
+			recycled++
+			continue
+		}
+		if !controllerutil.ContainsFinalizer(recycleItem, api.CountFinalizer) {
+			continue
+		}
+		if recycleItem.Labels[api.RestoredLabel] == "true" {
+			status.RestoredCount++
+		} else {
+			status.PurgedCount++
+		}
+		counted = append(counted, recycleItem)
+	}
+	status.RecycledCount = recycled + status.RestoredCount + status.PurgedCount
+	return status, counted
+}
+
+// releaseRecycleItems removes the CountFinalizer from the latest RecycleItems.
+func releaseRecycleItems(ctx context.Context, c client.Client, recycleItems []*api.RecycleItem) error {
+	for _, recycleItem := range recycleItems {
+		if !controllerutil.ContainsFinalizer(recycleItem, api.CountFinalizer) {
+			continue
+		}
+		if err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			latest := &api.RecycleItem{}
+			if err := c.Get(ctx, client.ObjectKeyFromObject(recycleItem), latest); err != nil {
+				return err
+			}
+			if !controllerutil.RemoveFinalizer(latest, api.CountFinalizer) {
+				return nil
+			}
+			return c.Update(ctx, latest)
+		}); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *RecyclePolicyCounter) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		// its own status updates don't change the counters
+		For(&api.RecyclePolicy{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
+		Watches(&api.RecycleItem{}, handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+			policyName, ok := obj.GetLabels()[api.RecyclePolicyLabel]
+			if !ok {
+				return nil
+			}
+			return []reconcile.Request{{NamespacedName: client.ObjectKey{Name: policyName}}}
+		})).
+		Named("recyclepolicy-counter").
+		Complete(r)
+}
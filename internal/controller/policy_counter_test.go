@@ -0,0 +1,78 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRecyclePolicyCounter(t *testing.T) {
+	newRecycleItem := func(name string, deleted bool, labels map[string]string) *api.RecycleItem {
+		result := &api.RecycleItem{ObjectMeta: metav1.ObjectMeta{
+			Name:       name,
+			Labels:     map[string]string{api.RecyclePolicyLabel: "deployments"},
+			Finalizers: []string{api.CountFinalizer},
+		}}
+		for k, v := range labels {
+			result.Labels[k] = v
+		}
+		if deleted {
+			result.DeletionTimestamp = &metav1.Time{Time: metav1.Now().Time}
+		}
+		return result
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithStatusSubresource(&api.RecyclePolicy{}).WithObjects(
+		&api.RecyclePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployments"},
+			Status:     api.RecyclePolicyStatus{RecycledCount: 5, RestoredCount: 2, PurgedCount: 1},
+		},
+		newRecycleItem("in-bin", false, nil),
+		newRecycleItem("restored", true, map[string]string{api.RestoredLabel: "true"}),
+		newRecycleItem("expired", true, nil),
+		&api.RecycleItem{ObjectMeta: metav1.ObjectMeta{Name: "other", Labels: map[string]string{api.RecyclePolicyLabel: "other"}}},
+	).Build()
+
+	counter := &RecyclePolicyCounter{Client: c, Scheme: scheme}
+	for range 2 {
+		// counting again doesn't count the released RecycleItems twice
+		if _, err := counter.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKey{Name: "deployments"}}); err != nil {
+			t.Fatalf("✗ failed to count RecycleItems: %v", err)
+		}
+	}
+
+	policy := &api.RecyclePolicy{}
+	if err := c.Get(context.Background(), client.ObjectKey{Name: "deployments"}, policy); err != nil {
+		t.Fatalf("✗ failed to get RecyclePolicy: %v", err)
+	}
+	if desired := (api.RecyclePolicyStatus{RecycledCount: 6, RestoredCount: 3, PurgedCount: 2}); policy.Status.RecycledCount != desired.RecycledCount ||
+		policy.Status.RestoredCount != desired.RestoredCount || policy.Status.PurgedCount != desired.PurgedCount {
+		t.Errorf("✗ expected counters %+v, got %+v", desired, policy.Status)
+	}
+	for _, name := range []string{"restored", "expired"} {
+		if err := c.Get(context.Background(), client.ObjectKey{Name: name}, &api.RecycleItem{}); !k8serrors.IsNotFound(err) {
+			t.Errorf("✗ expected RecycleItem [%s] released and deleted, got %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WebhookWatchdog switches the webhooks to the Ignore failure policy while the
+// krb-webhook Service has no ready endpoints, so deletions of recycled
+// resources aren't blocked across the cluster, and switches them back once
+// the webhook recovers.
+type WebhookWatchdog struct {
+	Reconciler *RecyclePolicyReconciler
+	Interval   time.Duration
+}
+
+// Start checks the webhook endpoints every interval until the context is done.
+func (w *WebhookWatchdog) Start(ctx context.Context) error {
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			w.check(ctx)
+		}
+	}
+}
+
+func (w *WebhookWatchdog) check(ctx context.Context) {
+	endpointSlices := &discoveryv1.EndpointSliceList{}
+	if err := w.Reconciler.List(ctx, endpointSlices, client.InNamespace(consts.WebhookNamespace), client.MatchingLabels{
+		discoveryv1.LabelServiceName: consts.WebhookName,
+	}); err != nil {
+		tlog.Errorf("✗ failed to list endpoints of webhook [%s]: %v", consts.WebhookName, err)
+		return
+	}
+
+	failOpen := !hasReadyEndpoint(endpointSlices.Items)
+	if w.Reconciler.failOpen.Swap(failOpen) == failOpen {
+		return
+	}
+	if failOpen {
+		tlog.Warnf("» webhook [%s] has no ready endpoints, switching webhooks to failure policy Ignore...", consts.WebhookName)
+	} else {
+		tlog.Infof("» webhook [%s] recovered, restoring webhook failure policies...", consts.WebhookName)
+	}
+
+	// The rebuild goes through the queue of the RecyclePolicy controller, so
+	// a reconcile that built the configuration with the previous failure
+	// policy can't overwrite it afterwards, and failed rebuilds are retried.
+	w.Reconciler.requestRebuild()
+}
+
+// hasReadyEndpoint reports whether any endpoint of the slices is ready, an
+// unknown readiness counts as ready.
+func hasReadyEndpoint(endpointSlices []discoveryv1.EndpointSlice) bool {
+	for _, endpointSlice := range endpointSlices {
+		for _, endpoint := range endpointSlice.Endpoints {
+			if endpoint.Conditions.Ready == nil || *endpoint.Conditions.Ready {
+				return true
+			}
+		}
+	}
+	return false
+}
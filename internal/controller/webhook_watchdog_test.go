@@ -0,0 +1,125 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/pkg/util"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+)
+
+func TestHasReadyEndpoint(t *testing.T) {
+	newEndpointSlice := func(ready ...*bool) discoveryv1.EndpointSlice {
+		var endpointSlice discoveryv1.EndpointSlice
+		for _, r := range ready {
+			endpointSlice.Endpoints = append(endpointSlice.Endpoints, discoveryv1.Endpoint{
+				Conditions: discoveryv1.EndpointConditions{Ready: r},
+			})
+		}
+		return endpointSlice
+	}
+
+	testdata := []struct {
+		name           string
+		endpointSlices []discoveryv1.EndpointSlice
+		desired        bool
+	}{
+		{
+			name: "no-endpoint-slices",
+		},
+		{
+			name:           "no-endpoints",
+			endpointSlices: []discoveryv1.EndpointSlice{newEndpointSlice()},
+		},
+		{
+			name:           "not-ready",
+			endpointSlices: []discoveryv1.EndpointSlice{newEndpointSlice(util.Ptr(false))},
+		},
+		{
+			name:           "ready-in-second-slice",
+			endpointSlices: []discoveryv1.EndpointSlice{newEndpointSlice(util.Ptr(false)), newEndpointSlice(util.Ptr(true))},
+			desired:        true,
+		},
+		{
+			name:           "unknown-readiness",
+			endpointSlices: []discoveryv1.EndpointSlice{newEndpointSlice(nil)},
+			desired:        true,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := hasReadyEndpoint(tt.endpointSlices); got != tt.desired {
+				t.Errorf("✗ expected %v, got %v", tt.desired, got)
+			}
+		})
+	}
+}
+
+func TestWebhookWatchdogCheck(t *testing.T) {
+	endpointSlice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.WebhookName + "-abcde",
+			Namespace: consts.WebhookNamespace,
+			Labels:    map[string]string{discoveryv1.LabelServiceName: consts.WebhookName},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints:   []discoveryv1.Endpoint{{Addresses: []string{"10.0.0.1"}, Conditions: discoveryv1.EndpointConditions{Ready: util.Ptr(false)}}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(endpointSlice).Build()
+	r := &RecyclePolicyReconciler{Client: c, rebuilds: make(chan event.GenericEvent, 1)}
+	w := &WebhookWatchdog{Reconciler: r}
+
+	testdata := []struct {
+		name     string
+		ready    bool
+		failOpen bool
+		rebuilt  bool
+	}{
+		{name: "down", failOpen: true, rebuilt: true},
+		{name: "still-down", failOpen: true},
+		{name: "recovered", ready: true, rebuilt: true},
+		{name: "still-up", ready: true},
+	}
+
+	for _, tt := range testdata {
+		endpointSlice.Endpoints[0].Conditions.Ready = util.Ptr(tt.ready)
+		if err := c.Update(context.Background(), endpointSlice); err != nil {
+			t.Fatalf("✗ failed to update endpoint slice: %v", err)
+		}
+		w.check(context.Background())
+		if failOpen := r.failOpen.Load(); failOpen != tt.failOpen {
+			t.Errorf("✗ %s: expected fail open %v, got %v", tt.name, tt.failOpen, failOpen)
+		}
+		// rebuilds are queued rather than run by the watchdog
+		rebuilt := false
+		select {
+		case <-r.rebuilds:
+			rebuilt = true
+		default:
+		}
+		if rebuilt != tt.rebuilt {
+			t.Errorf("✗ %s: expected rebuild %v, got %v", tt.name, tt.rebuilt, rebuilt)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// ProviderLocal encrypts data keys with the key held in the
+	// krb-encryption Secret.
+	ProviderLocal = "local"
+	// ProviderKMS encrypts data keys with a KMS plugin.
+	ProviderKMS = "kms"
+
+	dataKeySize = 32
+)
+
+// DefaultKinds are encrypted if the configuration lists no kinds.
+var DefaultKinds = []string{"Secret"}
+
+// ErrNotConfigured is returned when there is no krb-encryption Secret.
+var ErrNotConfigured = errors.New("encryption is not configured")
+
+// Config is the encryption configuration of recycled objects, read from the
+// encryption.yaml key of the krb-encryption Secret in krb-system.
+type Config struct {
+	// Kinds whose recycled objects are encrypted, in the form of Kind or
+	// Kind.group, such as Secret or Certificate.cert-manager.io. Defaults to
+	// Secret.
+	Kinds []string `json:"kinds,omitempty"`
+	// Provider of the key that encrypts data keys. One of: local|kms,
+	// defaults to local.
+	Provider string     `json:"provider,omitempty"`
+	KMS      *KMSConfig `json:"kms,omitempty"`
+
+	// Key of the local provider, read from the key key of the Secret.
+	Key []byte `json:"-"`
+}
+
+// KeyProvider encrypts and decrypts the data keys of payloads.
+type KeyProvider interface {
+	// Name of the provider, recorded in the encryption info of payloads.
+	Name() string
+	// Encrypt encrypts the data key, returning it with the ID of the key it
+	// is encrypted with.
+	Encrypt(ctx context.Context, dataKey []byte) ([]byte, string, error)
+	// Decrypt decrypts the data key encrypted with the key of the ID.
+	Decrypt(ctx context.Context, keyID string, encryptedKey []byte) ([]byte, error)
+}
+
+// LoadConfig loads the encryption configuration from the krb-encryption
+// Secret, ErrNotConfigured is returned if there is none.
+func LoadConfig(ctx context.Context, client kubernetes.Interface) (*Config, error) {
+	secret, err := client.CoreV1().Secrets(consts.WebhookNamespace).Get(ctx, consts.EncryptionSecretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return nil, ErrNotConfigured
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret [%s]: %w", consts.EncryptionSecretName, err)
+	}
+	return parseConfig(secret)
+}
+
+// EnsureConfig loads the encryption configuration from the krb-encryption
+// Secret, creating it with a generated key of the local provider and the
+// default kinds if there is none.
+func EnsureConfig(ctx context.Context, client kubernetes.Interface) (*Config, error) {
+	config, err := LoadConfig(ctx, client)
+	if !errors.Is(err, ErrNotConfigured) {
+		return config, err
+	}
+
+	key := make([]byte, dataKeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(&Config{Kinds: DefaultKinds, Provider: ProviderLocal})
+	if err != nil {
+		return nil, err
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      consts.EncryptionSecretName,
+			Namespace: consts.WebhookNamespace,
+		},
+		Data: map[string][]byte{
+			consts.EncryptionSecretConfigKey: data,
+			consts.EncryptionSecretKeyKey:    key,
+		},
+	}
+	if _, err := client.CoreV1().Secrets(consts.WebhookNamespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
+		if k8serrors.IsAlreadyExists(err) {
+			return LoadConfig(ctx, client)
+		}
+		return nil, fmt.Errorf("failed to create secret [%s]: %w", consts.EncryptionSecretName, err)
+	}
+	return parseConfig(secret)
+}
+
+func parseConfig(secret *corev1.Secret) (*Config, error) {
+	config := &Config{}
+	if err := yaml.Unmarshal(secret.Data[consts.EncryptionSecretConfigKey], config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of secret [%s]: %w", consts.EncryptionSecretConfigKey, consts.EncryptionSecretName, err)
+	}
+	config.Key = secret.Data[consts.EncryptionSecretKeyKey]
+	return config, nil
+}
+
+// Encryptor encrypts and decrypts the payloads of recycled objects with
+// envelope encryption.
+type Encryptor struct {
+	kinds    []schema.GroupKind
+	provider KeyProvider
+}
+
+// NewEncryptor creates the encryptor of the configuration.
+func NewEncryptor(config *Config) (*Encryptor, error) {
+	var provider KeyProvider
+	var err error
+	switch config.Provider {
+	case "", ProviderLocal:
+		provider, err = NewLocalProvider(config.Key)
+	case ProviderKMS:
+		if config.KMS == nil {
+			return nil, fmt.Errorf("kms provider requires the kms configuration")
+		}
+		provider, err = NewKMSProvider(config.KMS)
+	default:
+		return nil, fmt.Errorf("unknown encryption provider %q, must be one of: local|kms", config.Provider)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kinds := config.Kinds
+	if len(kinds) == 0 {
+		kinds = DefaultKinds
+	}
+	encryptor := &Encryptor{provider: provider}
+	for _, kind := range kinds {
+		encryptor.kinds = append(encryptor.kinds, schema.ParseGroupKind(kind))
+	}
+	return encryptor, nil
+}
+
+// Encrypts reports whether recycled objects of the kind are encrypted.
+func (e *Encryptor) Encrypts(gk schema.GroupKind) bool {
+	return slices.Contains(e.kinds, gk)
+}
+
+// Encrypt encrypts Raw of the recycled object with a random data key, which
+// is encrypted by the key provider. The ciphertext is bound to the group,
+// resource, namespace and name of the object.
+func (e *Encryptor) Encrypt(ctx context.Context, obj *api.RecycledObject) error {
+	if obj.Encryption != nil {
+		return nil
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return err
+	}
+	ciphertext, err := seal(dataKey, obj.Raw, additionalData(obj))
+	if err != nil {
+		return err
+	}
+	encryptedKey, keyID, err := e.provider.Encrypt(ctx, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt data key with %s provider: %w", e.provider.Name(), err)
+	}
+
+	obj.Raw = ciphertext
+	obj.Encryption = &api.EncryptionInfo{
+		Provider:     e.provider.Name(),
+		KeyID:        keyID,
+		EncryptedKey: encryptedKey,
+	}
+	return nil
+}
+
+// Decrypt decrypts Raw of the recycled object.
+func (e *Encryptor) Decrypt(ctx context.Context, obj *api.RecycledObject) error {
+	if obj.Encryption == nil {
+		return nil
+	}
+	if obj.Encryption.Provider != e.provider.Name() {
+		return fmt.Errorf("payload is encrypted by %s provider, but %s provider is configured", obj.Encryption.Provider, e.provider.Name())
+	}
+
+	dataKey, err := e.provider.Decrypt(ctx, obj.Encryption.KeyID, obj.Encryption.EncryptedKey)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt data key with %s provider: %w", e.provider.Name(), err)
+	}
+	plaintext, err := open(dataKey, obj.Raw, additionalData(obj))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt payload: %w", err)
+	}
+
+	obj.Raw = plaintext
+	obj.Encryption = nil
+	return nil
+}
+
+func additionalData(obj *api.RecycledObject) []byte {
+	return []byte(obj.GroupResource().String() + "/" + obj.Key())
+}
+
+// seal encrypts the plaintext with AES-GCM, prefixing the random nonce.
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts the ciphertext sealed by seal.
+func open(key, ciphertext, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+const secretData = `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"db","namespace":"dev"},"data":{"password":"cGFzc3dvcmQ="}}`
+
+func TestEncryptor(t *testing.T) {
+	// fake KMS plugin, which "encrypts" by reversing the data key
+	kms := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &kmsRequest{}
+		json.NewDecoder(r.Body).Decode(request)
+		reverse := func(b []byte) []byte {
+			reversed := make([]byte, len(b))
+			for i := range b {
+				reversed[len(b)-1-i] = b[i]
+			}
+			return reversed
+		}
+		switch r.URL.Path {
+		case "/encrypt":
+			json.NewEncoder(w).Encode(&kmsRequest{KeyID: "kms-key", Ciphertext: reverse(request.Plaintext)})
+		case "/decrypt":
+			json.NewEncoder(w).Encode(&kmsRequest{Plaintext: reverse(request.Ciphertext)})
+		}
+	}))
+	defer kms.Close()
+
+	config, err := EnsureConfig(context.Background(), fake.NewClientset())
+	if err != nil {
+		t.Fatalf("✗ failed to ensure config: %v", err)
+	}
+
+	testdata := []struct {
+		name   string
+		config *Config
+	}{
+		{name: "local", config: config},
+		{name: "kms", config: &Config{Provider: ProviderKMS, KMS: &KMSConfig{Endpoint: kms.URL}}},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptor, err := NewEncryptor(tt.config)
+			if err != nil {
+				t.Fatalf("✗ failed to create encryptor: %v", err)
+			}
+			if !encryptor.Encrypts(schema.GroupKind{Kind: "Secret"}) || encryptor.Encrypts(schema.GroupKind{Kind: "ConfigMap"}) {
+				t.Errorf("✗ expected only Secrets encrypted by default")
+			}
+
+			obj := &api.RecycledObject{Version: "v1", Kind: "Secret", Resource: "secrets", Namespace: "dev", Name: "db", Raw: []byte(secretData)}
+			if err := encryptor.Encrypt(context.Background(), obj); err != nil {
+				t.Fatalf("✗ failed to encrypt: %v", err)
+			}
+			if obj.Encryption == nil || obj.Encryption.Provider != tt.name || string(obj.Raw) == secretData {
+				t.Fatalf("✗ expected payload encrypted by %s provider, got %+v", tt.name, obj.Encryption)
+			}
+			if _, err := obj.Data(); err != api.ErrEncrypted {
+				t.Errorf("✗ expected %v, got %v", api.ErrEncrypted, err)
+			}
+
+			// payloads can't be moved to another object
+			moved := &api.RecycledObject{}
+			obj.DeepCopyInto(moved)
+			moved.Namespace = "prod"
+			if err := encryptor.Decrypt(context.Background(), moved); err == nil {
+				t.Errorf("✗ expected payload of another object not decrypted")
+			}
+
+			if err := encryptor.Decrypt(context.Background(), obj); err != nil {
+				t.Fatalf("✗ failed to decrypt: %v", err)
+			}
+			if data, err := obj.Data(); err != nil || string(data) != secretData {
+				t.Errorf("✗ expected %s, got %s, %v", secretData, data, err)
+			}
+		})
+	}
+}
+
+func TestEncryptorKinds(t *testing.T) {
+	encryptor, err := NewEncryptor(&Config{Kinds: []string{"Secret", "Certificate.cert-manager.io"}, Key: make([]byte, dataKeySize)})
+	if err != nil {
+		t.Fatalf("✗ failed to create encryptor: %v", err)
+	}
+
+	testdata := []struct {
+		gk       schema.GroupKind
+		encrypts bool
+	}{
+		{gk: schema.GroupKind{Kind: "Secret"}, encrypts: true},
+		{gk: schema.GroupKind{Group: "cert-manager.io", Kind: "Certificate"}, encrypts: true},
+		{gk: schema.GroupKind{Kind: "Certificate"}},
+		{gk: schema.GroupKind{Kind: "ConfigMap"}},
+	}
+
+	for _, tt := range testdata {
+		if encrypts := encryptor.Encrypts(tt.gk); encrypts != tt.encrypts {
+			t.Errorf("✗ expected %s encrypted %v, got %v", tt.gk.String(), tt.encrypts, encrypts)
+		}
+	}
+}
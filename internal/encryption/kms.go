@@ -0,0 +1,122 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// KMSConfig configures the KMS provider, which delegates encrypting data keys
+// to a KMS plugin, usually a sidecar of krb-webhook.
+//
+// The plugin serves two endpoints, exchanging JSON with base64 encoded keys:
+//
+//	POST /encrypt {"keyID": "...", "plaintext": "..."} -> {"keyID": "...", "ciphertext": "..."}
+//	POST /decrypt {"keyID": "...", "ciphertext": "..."} -> {"plaintext": "..."}
+type KMSConfig struct {
+	// Endpoint of the KMS plugin, such as http://127.0.0.1:8200.
+	Endpoint string `json:"endpoint"`
+	// KeyID of the key the plugin encrypts data keys with, empty for the
+	// default key of the plugin.
+	KeyID string `json:"keyID,omitempty"`
+}
+
+type kmsRequest struct {
+	KeyID      string `json:"keyID,omitempty"`
+	Plaintext  []byte `json:"plaintext,omitempty"`
+	Ciphertext []byte `json:"ciphertext,omitempty"`
+}
+
+type kmsProvider struct {
+	endpoint string
+	keyID    string
+	client   *http.Client
+}
+
+// NewKMSProvider creates a key provider encrypting data keys with the KMS
+// plugin.
+func NewKMSProvider(config *KMSConfig) (KeyProvider, error) {
+	if u, err := url.Parse(config.Endpoint); err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid kms endpoint %q", config.Endpoint)
+	}
+	return &kmsProvider{
+		endpoint: strings.TrimSuffix(config.Endpoint, "/"),
+		keyID:    config.KeyID,
+		client:   &http.Client{Timeout: 5 * time.Second},
+	}, nil
+}
+
+func (p *kmsProvider) Name() string {
+	return ProviderKMS
+}
+
+func (p *kmsProvider) Encrypt(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	response, err := p.call(ctx, "/encrypt", &kmsRequest{KeyID: p.keyID, Plaintext: dataKey})
+	if err != nil {
+		return nil, "", err
+	}
+	if len(response.Ciphertext) == 0 {
+		return nil, "", fmt.Errorf("kms plugin returned no ciphertext")
+	}
+	return response.Ciphertext, response.KeyID, nil
+}
+
+func (p *kmsProvider) Decrypt(ctx context.Context, keyID string, encryptedKey []byte) ([]byte, error) {
+	response, err := p.call(ctx, "/decrypt", &kmsRequest{KeyID: keyID, Ciphertext: encryptedKey})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Plaintext) != dataKeySize {
+		return nil, fmt.Errorf("kms plugin returned a %d-byte data key", len(response.Plaintext))
+	}
+	return response.Plaintext, nil
+}
+
+func (p *kmsProvider) call(ctx context.Context, path string, request *kmsRequest) (*kmsRequest, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("kms plugin %s: %s: %s", path, resp.Status, bytes.TrimSpace(message))
+	}
+
+	response := &kmsRequest{}
+	if err := json.NewDecoder(resp.Body).Decode(response); err != nil {
+		return nil, fmt.Errorf("failed to decode response of kms plugin: %w", err)
+	}
+	return response, nil
+}
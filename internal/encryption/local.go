@@ -0,0 +1,54 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package encryption
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
+type localProvider struct {
+	key   []byte
+	keyID string
+}
+
+// NewLocalProvider creates a key provider encrypting data keys with the
+// 32-byte key, identified by its SHA-256 fingerprint.
+func NewLocalProvider(key []byte) (KeyProvider, error) {
+	if len(key) != dataKeySize {
+		return nil, fmt.Errorf("local provider requires a %d-byte key, got %d bytes", dataKeySize, len(key))
+	}
+	fingerprint := sha256.Sum256(key)
+	return &localProvider{key: key, keyID: hex.EncodeToString(fingerprint[:8])}, nil
+}
+
+func (p *localProvider) Name() string {
+	return ProviderLocal
+}
+
+func (p *localProvider) Encrypt(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	encryptedKey, err := seal(p.key, dataKey, nil)
+	return encryptedKey, p.keyID, err
+}
+
+func (p *localProvider) Decrypt(ctx context.Context, keyID string, encryptedKey []byte) ([]byte, error) {
+	if keyID != p.keyID {
+		return nil, fmt.Errorf("data key is encrypted with key %s, but the key is %s", keyID, p.keyID)
+	}
+	return open(p.key, encryptedKey, nil)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// configMapChunkSize keeps the base64 encoded chunk of a ConfigMap well
+	// below the 1MiB limit.
+	configMapChunkSize  = 512 * 1024
+	configMapDataKey    = "payload"
+	configMapChunkInfix = "-payload-"
+)
+
+type configMapBackend struct {
+	client kubernetes.Interface
+}
+
+// NewConfigMapBackend creates a backend storing payloads in ConfigMaps in
+// krb-system, split into chunks named <key>-payload-<index>.
+func NewConfigMapBackend(client kubernetes.Interface) Backend {
+	return &configMapBackend{client: client}
+}
+
+func (b *configMapBackend) Name() string {
+	return BackendConfigMap
+}
+
+func (b *configMapBackend) Put(ctx context.Context, key string, data []byte) error {
+	if err := b.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	for i := 0; i*configMapChunkSize < len(data); i++ {
+		configMap := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      configMapChunkName(key, i),
+				Namespace: consts.WebhookNamespace,
+				Labels: map[string]string{
+					api.RecycleItemLabel: key,
+				},
+			},
+			BinaryData: map[string][]byte{
+				configMapDataKey: data[i*configMapChunkSize : min((i+1)*configMapChunkSize, len(data))],
+			},
+		}
+		if _, err := b.client.CoreV1().ConfigMaps(consts.WebhookNamespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create payload ConfigMap [%s]: %w", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+func (b *configMapBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	configMaps, err := b.list(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if len(configMaps) == 0 {
+		return nil, ErrNotFound
+	}
+
+	chunks := make([][]byte, len(configMaps))
+	for _, configMap := range configMaps {
+		index, err := strconv.Atoi(strings.TrimPrefix(configMap.Name, key+configMapChunkInfix))
+		if err != nil || index < 0 || index >= len(chunks) {
+			return nil, fmt.Errorf("unexpected payload ConfigMap [%s]", configMap.Name)
+		}
+		chunks[index] = configMap.BinaryData[configMapDataKey]
+	}
+	if slices.ContainsFunc(chunks, func(chunk []byte) bool { return chunk == nil }) {
+		return nil, fmt.Errorf("payload ConfigMaps of [%s] are incomplete", key)
+	}
+	return bytes.Join(chunks, nil), nil
+}
+
+func (b *configMapBackend) Delete(ctx context.Context, key string) error {
+	configMaps, err := b.list(ctx, key)
+	if err != nil {
+		return err
+	}
+	for _, configMap := range configMaps {
+		if err := b.client.CoreV1().ConfigMaps(consts.WebhookNamespace).Delete(ctx, configMap.Name, metav1.DeleteOptions{}); err != nil && !k8serrors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete payload ConfigMap [%s]: %w", configMap.Name, err)
+		}
+	}
+	return nil
+}
+
+func (b *configMapBackend) list(ctx context.Context, key string) ([]corev1.ConfigMap, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	configMaps, err := b.client.CoreV1().ConfigMaps(consts.WebhookNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: api.RecycleItemLabel + "=" + key,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list payload ConfigMaps: %w", err)
+	}
+	return configMaps.Items, nil
+}
+
+func configMapChunkName(key string, index int) string {
+	return key + configMapChunkInfix + strconv.Itoa(index)
+}
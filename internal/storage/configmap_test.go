@@ -0,0 +1,46 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestConfigMapBackend(t *testing.T) {
+	client := fake.NewClientset()
+	backend := NewConfigMapBackend(client)
+	testBackend(t, backend)
+
+	// large payloads are split into chunks
+	payload := bytes.Repeat([]byte("0123456789"), configMapChunkSize/4)
+	if err := backend.Put(context.Background(), "large", payload); err != nil {
+		t.Fatalf("✗ failed to put payload: %v", err)
+	}
+	configMaps, _ := client.CoreV1().ConfigMaps(consts.WebhookNamespace).List(context.Background(), metav1.ListOptions{})
+	if len(configMaps.Items) != 3 {
+		t.Errorf("✗ expected 3 chunks, got %d", len(configMaps.Items))
+	}
+	data, err := backend.Get(context.Background(), "large")
+	if err != nil || !bytes.Equal(data, payload) {
+		t.Errorf("✗ expected the payload joined from chunks, got %d bytes, %v", len(data), err)
+	}
+}
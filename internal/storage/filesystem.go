@@ -0,0 +1,103 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FilesystemConfig configures the filesystem backend.
+type FilesystemConfig struct {
+	// Path of the directory payloads are stored in.
+	Path string `json:"path"`
+}
+
+type filesystemBackend struct {
+	dir string
+}
+
+// NewFilesystemBackend creates a backend storing payloads as files in the
+// directory, creating it if it doesn't exist.
+func NewFilesystemBackend(dir string) (Backend, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("filesystem backend requires a path")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	return &filesystemBackend{dir: dir}, nil
+}
+
+func (b *filesystemBackend) Name() string {
+	return BackendFilesystem
+}
+
+func (b *filesystemBackend) Put(ctx context.Context, key string, data []byte) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a payload is never read partially
+	file, err := os.CreateTemp(b.dir, ".tmp-"+key+"-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), b.path(key))
+}
+
+func (b *filesystemBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(b.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (b *filesystemBackend) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := os.Remove(b.path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (b *filesystemBackend) path(key string) string {
+	return filepath.Join(b.dir, key)
+}
@@ -0,0 +1,34 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFilesystemBackend(t *testing.T) {
+	dir := t.TempDir()
+	backend, err := NewFilesystemBackend(dir)
+	if err != nil {
+		t.Fatalf("✗ failed to create backend: %v", err)
+	}
+
+	testBackend(t, backend)
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("✗ expected no files left, got %d", len(entries))
+	}
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/rest"
+)
+
+type remoteBackend struct {
+	client rest.Interface
+	name   string
+	token  string
+}
+
+// NewRemoteBackend creates a backend reading and deleting the payloads stored
+// by krb-webhook in the named backend through its payloads endpoint, which is
+// reached by the API server proxy of the krb-webhook Service.
+func NewRemoteBackend(client rest.Interface, name, token string) Backend {
+	return &remoteBackend{client: client, name: name, token: token}
+}
+
+func (b *remoteBackend) Name() string {
+	return b.name
+}
+
+func (b *remoteBackend) Put(ctx context.Context, key string, data []byte) error {
+	return fmt.Errorf("payloads can only be stored by krb-webhook")
+}
+
+func (b *remoteBackend) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ValidateKey(key); err != nil {
+		return nil, err
+	}
+
+	data, err := b.request(http.MethodGet, key).DoRaw(ctx)
+	if k8serrors.IsNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payload from krb-webhook: %w", err)
+	}
+	return data, nil
+}
+
+func (b *remoteBackend) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	if err := b.request(http.MethodDelete, key).Do(ctx).Error(); err != nil && !k8serrors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete payload by krb-webhook: %w", err)
+	}
+	return nil
+}
+
+func (b *remoteBackend) request(method, key string) *rest.Request {
+	return b.client.Verb(method).
+		AbsPath("/api/v1/namespaces", consts.WebhookNamespace, "services", "https:"+consts.WebhookName+":443", "proxy", consts.WebhookPayloadsPath, key).
+		Param("backend", b.name).
+		SetHeader(consts.PayloadTokenHeader, b.token)
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// BackendConfigMap stores payloads in chunked ConfigMaps in krb-system.
+	BackendConfigMap = "configmap"
+	// BackendFilesystem stores payloads as files, usually on a PVC mounted
+	// into krb-webhook.
+	BackendFilesystem = "filesystem"
+	// BackendS3 stores payloads as objects in an S3-compatible bucket.
+	BackendS3 = "s3"
+)
+
+// ErrNotFound is returned when no payload is stored under the key.
+var ErrNotFound = errors.New("payload not found")
+
+// Backend stores the payloads of recycled objects outside of RecycleItems,
+// keyed by the name of their RecycleItem.
+type Backend interface {
+	// Name of the backend, recorded in the payload references.
+	Name() string
+	// Put stores the payload under the key, replacing any existing one.
+	Put(ctx context.Context, key string, data []byte) error
+	// Get returns the payload stored under the key, or ErrNotFound.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Delete deletes the payload stored under the key, if any.
+	Delete(ctx context.Context, key string) error
+}
+
+// Config is the storage configuration of krb-webhook, read from the
+// storage.yaml key of the krb-storage Secret in krb-system.
+type Config struct {
+	// Backend every recycled object is stored in, empty to store them in
+	// their RecycleItems. One of: configmap|filesystem|s3
+	Backend    string            `json:"backend,omitempty"`
+	Filesystem *FilesystemConfig `json:"filesystem,omitempty"`
+	S3         *S3Config         `json:"s3,omitempty"`
+}
+
+// LoadConfig loads the storage configuration from the krb-storage Secret, an
+// empty configuration is returned if there is none.
+func LoadConfig(ctx context.Context, client kubernetes.Interface) (*Config, error) {
+	secret, err := client.CoreV1().Secrets(consts.WebhookNamespace).Get(ctx, consts.StorageSecretName, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return &Config{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get secret [%s]: %w", consts.StorageSecretName, err)
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(secret.Data[consts.StorageSecretConfigKey], config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s of secret [%s]: %w", consts.StorageSecretConfigKey, consts.StorageSecretName, err)
+	}
+	return config, nil
+}
+
+// LoadToken loads the token of the payloads endpoint of krb-webhook from the
+// Secret in krb-system, one of krb-payload-reader to read payloads and
+// krb-payload-deleter to delete them.
+func LoadToken(ctx context.Context, client kubernetes.Interface, secretName string) (string, error) {
+	secret, err := client.CoreV1().Secrets(consts.WebhookNamespace).Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to get secret [%s]: %w", secretName, err)
+	}
+	token := string(secret.Data[consts.PayloadTokenSecretKey])
+	if token == "" {
+		return "", fmt.Errorf("secret [%s] has no %s to access the payloads of krb-webhook", secretName, consts.PayloadTokenSecretKey)
+	}
+	return token, nil
+}
+
+// New creates the backend of the configuration, nil if recycled objects are
+// stored in their RecycleItems.
+func New(config *Config, client kubernetes.Interface) (Backend, error) {
+	switch config.Backend {
+	case "":
+		return nil, nil
+	case BackendConfigMap:
+		return NewConfigMapBackend(client), nil
+	case BackendFilesystem:
+		if config.Filesystem == nil {
+			return nil, fmt.Errorf("filesystem backend requires the filesystem configuration")
+		}
+		return NewFilesystemBackend(config.Filesystem.Path)
+	case BackendS3:
+		if config.S3 == nil {
+			return nil, fmt.Errorf("s3 backend requires the s3 configuration")
+		}
+		return NewS3Backend(config.S3)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q, must be one of: configmap|filesystem|s3", config.Backend)
+}
+
+// Open opens the named backend for reading or deleting payloads from outside
+// of krb-webhook. ConfigMaps are accessed directly, other backends through
+// the payloads endpoint of krb-webhook, so the storage itself only has to be
+// reachable from krb-webhook. The endpoint is authorized by the token in the
+// named Secret, krb-payload-reader to read payloads or krb-payload-deleter to
+// delete them, so neither needs access to the storage credentials.
+func Open(ctx context.Context, client kubernetes.Interface, name, tokenSecretName string) (Backend, error) {
+	switch name {
+	case BackendConfigMap:
+		return NewConfigMapBackend(client), nil
+	case BackendFilesystem, BackendS3:
+		token, err := LoadToken(ctx, client, tokenSecretName)
+		if err != nil {
+			return nil, err
+		}
+		return NewRemoteBackend(client.CoreV1().RESTClient(), name, token), nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", name)
+}
+
+// ValidateKey rejects keys that are not valid RecycleItem names, so they are
+// safe to use in file names, object keys and URL paths.
+func ValidateKey(key string) error {
+	if errs := validation.IsDNS1123Subdomain(key); len(errs) > 0 {
+		return fmt.Errorf("invalid payload key %q: %v", key, errs)
+	}
+	return nil
+}
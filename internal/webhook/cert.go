@@ -13,58 +13,149 @@ WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
 See the License for the specific language governing permissions and
 limitations under the License.
 */
-
 package webhook
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
+	"github.com/ketches/kube-recycle-bin/internal/certs"
 	"github.com/ketches/kube-recycle-bin/internal/consts"
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
-	corev1 "k8s.io/api/core/v1"
-	k8serrors "k8s.io/apimachinery/pkg/api/errors"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	certuitl "k8s.io/client-go/util/cert"
 )
 
-var (
-	cert, key []byte
-)
+// servingCert is the serving certificate of the webhook, reloaded after it
+// is rotated.
+var servingCert atomic.Pointer[tls.Certificate]
 
-func FetchWebhookCertAndKey() ([]byte, []byte) {
-	if cert == nil {
-		if secret, err := kube.Client().CoreV1().Secrets(consts.WebhookNamespace).Get(context.Background(), consts.WebhookTLSCertSecretName, metav1.GetOptions{}); err != nil {
-			if k8serrors.IsNotFound(err) {
-				cert, key, err = certuitl.GenerateSelfSignedCertKey(consts.WebhookDNSName, nil, []string{consts.WebhookDNSName})
-				if err != nil {
-					tlog.Fatalf("✗ failed to generate self-signed cert and key: %v", err)
-				}
-				secret = &corev1.Secret{
-					ObjectMeta: metav1.ObjectMeta{
-						Name:      consts.WebhookTLSCertSecretName,
-						Namespace: consts.WebhookNamespace,
-					},
-					Data: map[string][]byte{
-						corev1.TLSCertKey:       cert,
-						corev1.TLSPrivateKeyKey: key,
-					},
-					Type: corev1.SecretTypeTLS,
-				}
-				if _, err := kube.Client().CoreV1().Secrets(consts.WebhookNamespace).Create(context.Background(), secret, metav1.CreateOptions{}); err != nil {
-					if !k8serrors.IsAlreadyExists(err) {
-						tlog.Fatalf("✗ failed to create secret [%s]: %v", consts.WebhookTLSCertSecretName, err)
-					}
-				}
-				tlog.Infof("✓ cert secret [%s] created.", consts.WebhookTLSCertSecretName)
-			} else {
-				tlog.Fatalf("✗ failed to get secret [%s]: %v", consts.WebhookTLSCertSecretName, err)
+// certLoader loads the serving certificate.
+type certLoader func(ctx context.Context) (*certs.Bundle, error)
+
+// setupCertificate loads the serving certificate from tls.crt and tls.key in
+// certDir, such as a mounted Secret of cert-manager, and reloads it whenever
+// they change. Without certDir it is loaded from the krb-webhook-tls Secret,
+// created if there is none, and reloaded every interval after krb-controller
+// rotates it.
+func setupCertificate(ctx context.Context, certDir string, interval time.Duration) {
+	load := loadCertSecret
+	var events <-chan fsnotify.Event
+	if certDir != "" {
+		load = certDirLoader(certDir)
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			tlog.Fatalf("✗ failed to watch cert dir [%s]: %v", certDir, err)
+		}
+		// mounted Secrets are updated by swapping a symlink in the directory
+		if err := watcher.Add(certDir); err != nil {
+			tlog.Fatalf("✗ failed to watch cert dir [%s]: %v", certDir, err)
+		}
+		events = watcher.Events
+		go func() {
+			<-ctx.Done()
+			watcher.Close()
+		}()
+	} else if _, _, err := certs.Ensure(ctx, kube.Client(), certs.DefaultOptions, false); err != nil {
+		tlog.Fatalf("✗ %v", err)
+	}
+
+	bundle, err := load(ctx)
+	if err == nil {
+		err = storeCertificate(bundle)
+	}
+	if err != nil {
+		tlog.Fatalf("✗ failed to load serving certificate: %v", err)
+	}
+	tlog.Infof("✓ serving certificate loaded, valid until %s.", bundle.NotAfter().Format(time.RFC3339))
+
+	go reloadCertificate(ctx, load, interval, events)
+}
+
+// reloadCertificate reloads the serving certificate every interval and on
+// every event until the context is done.
+func reloadCertificate(ctx context.Context, load certLoader, interval time.Duration, events <-chan fsnotify.Event) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case _, ok := <-events:
+			if !ok {
+				events = nil
+				continue
 			}
-		} else {
-			tlog.Infof("✓ cert secret [%s] found.", consts.WebhookTLSCertSecretName)
-			cert = secret.Data[corev1.TLSCertKey]
-			key = secret.Data[corev1.TLSPrivateKeyKey]
 		}
+
+		bundle, err := load(ctx)
+		if err != nil {
+			tlog.Errorf("✗ failed to reload serving certificate: %v", err)
+			continue
+		}
+		if current := servingCert.Load(); current != nil && bytes.Equal(current.Certificate[0], certDER(bundle)) {
+			continue
+		}
+		if err := storeCertificate(bundle); err != nil {
+			tlog.Errorf("✗ invalid serving certificate: %v", err)
+			continue
+		}
+		tlog.Infof("✓ serving certificate reloaded, valid until %s.", bundle.NotAfter().Format(time.RFC3339))
+	}
+}
+
+func loadCertSecret(ctx context.Context) (*certs.Bundle, error) {
+	return certs.Load(ctx, kube.Client())
+}
+
+// certDirLoader loads the serving certificate from tls.crt and tls.key in the
+// directory.
+func certDirLoader(dir string) certLoader {
+	return func(context.Context) (*certs.Bundle, error) {
+		cert, err := os.ReadFile(filepath.Join(dir, consts.WebhookServiceTLSCertFile))
+		if err != nil {
+			return nil, err
+		}
+		key, err := os.ReadFile(filepath.Join(dir, consts.WebhookServiceTLSKeyFile))
+		if err != nil {
+			return nil, err
+		}
+		return &certs.Bundle{Cert: cert, Key: key}, nil
+	}
+}
+
+func storeCertificate(bundle *certs.Bundle) error {
+	cert, err := bundle.TLSCertificate()
+	if err != nil {
+		return err
+	}
+	servingCert.Store(cert)
+	certificateExpiry.Set(float64(cert.Leaf.NotAfter.Unix()))
+	return nil
+}
+
+// certDER returns the DER encoded serving certificate of the bundle.
+func certDER(bundle *certs.Bundle) []byte {
+	cert, err := bundle.TLSCertificate()
+	if err != nil {
+		return nil
+	}
+	return cert.Certificate[0]
+}
+
+// getCertificate serves the current serving certificate.
+func getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := servingCert.Load()
+	if cert == nil {
+		return nil, errors.New("no serving certificate")
 	}
-	return cert, key
+	return cert, nil
 }
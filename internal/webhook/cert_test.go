@@ -0,0 +1,86 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/ketches/kube-recycle-bin/internal/certs"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+)
+
+func TestReloadCertificateFromCertDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func() *certs.Bundle {
+		bundle, err := certs.Generate(certs.DefaultOptions, time.Now())
+		if err != nil {
+			t.Fatalf("✗ failed to generate certificate: %v", err)
+		}
+		// written like a mounted Secret, replacing a symlinked directory
+		data := filepath.Join(dir, "..data-"+time.Now().Format("150405.000000000"))
+		if err := os.Mkdir(data, 0o755); err != nil {
+			t.Fatalf("✗ failed to create data dir: %v", err)
+		}
+		_ = os.WriteFile(filepath.Join(data, consts.WebhookServiceTLSCertFile), bundle.Cert, 0o600)
+		_ = os.WriteFile(filepath.Join(data, consts.WebhookServiceTLSKeyFile), bundle.Key, 0o600)
+		link := filepath.Join(dir, "..data-tmp")
+		_ = os.Symlink(filepath.Base(data), link)
+		if err := os.Rename(link, filepath.Join(dir, "..data")); err != nil {
+			t.Fatalf("✗ failed to swap data dir: %v", err)
+		}
+		for _, name := range []string{consts.WebhookServiceTLSCertFile, consts.WebhookServiceTLSKeyFile} {
+			_ = os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name))
+		}
+		return bundle
+	}
+
+	first := write()
+	load := certDirLoader(dir)
+	bundle, err := load(context.Background())
+	if err != nil || !bytes.Equal(bundle.Cert, first.Cert) {
+		t.Fatalf("✗ failed to load certificate: %v", err)
+	}
+	if err := storeCertificate(bundle); err != nil {
+		t.Fatalf("✗ failed to store certificate: %v", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		t.Fatalf("✗ failed to watch cert dir: %v", err)
+	}
+	defer watcher.Close()
+	if err := watcher.Add(dir); err != nil {
+		t.Fatalf("✗ failed to watch cert dir: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go reloadCertificate(ctx, load, time.Hour, watcher.Events)
+
+	second := write()
+	desired := certDER(second)
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if cert, _ := getCertificate(nil); bytes.Equal(cert.Certificate[0], desired) {
+			return
+		}
+	}
+	t.Errorf("✗ expected the rewritten certificate to be reloaded")
+}
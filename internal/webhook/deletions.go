@@ -0,0 +1,83 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// deletions remembers the recent deletions, as the API server calls every
+// webhook of the configuration matching a deletion, which recycles an object
+// targeted by RecyclePolicies with different selectors more than once.
+var deletions = &recentDeletions{TTL: time.Minute}
+
+// recentDeletions are the deletions seen within the TTL. They are keyed by
+// the UID and resource version of the deleted object rather than the UID of
+// the admission request, which is generated for every webhook called.
+type recentDeletions struct {
+	TTL time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+}
+
+// deletionKey returns the key of the deletion of the object, or an empty
+// string if the object has no UID.
+func deletionKey(recycledObj *api.RecycledObject, objectMeta *metav1.ObjectMeta) string {
+	if objectMeta.UID == "" {
+		return ""
+	}
+	return recycledObj.GroupResource().String() + "/" + string(objectMeta.UID) + "/" + objectMeta.ResourceVersion
+}
+
+// Seen records the deletion and reports whether it has been seen within the
+// TTL. Deletions without a key are never seen. A deletion recorded by Seen
+// must be forgotten if it isn't recycled, so it is recycled when retried.
+func (d *recentDeletions) Seen(key string, now time.Time) bool {
+	if key == "" {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.seen == nil {
+		d.seen = map[string]time.Time{}
+	}
+	if at, ok := d.seen[key]; ok && now.Sub(at) < d.TTL {
+		return true
+	}
+	// Expired deletions are dropped as new ones are recorded, so the map
+	// holds the deletions of one TTL at most.
+	for k, at := range d.seen {
+		if now.Sub(at) >= d.TTL {
+			delete(d.seen, k)
+		}
+	}
+	d.seen[key] = now
+	return false
+}
+
+// Forget drops the deletion, which has been denied or failed to be recycled.
+func (d *recentDeletions) Forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.seen, key)
+}
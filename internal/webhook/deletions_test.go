@@ -0,0 +1,71 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRecentDeletions(t *testing.T) {
+	recycledObj := &api.RecycledObject{Group: "apps", Resource: "deployments", Namespace: "dev", Name: "nginx"}
+	key := deletionKey(recycledObj, &metav1.ObjectMeta{UID: "6b1c1f6e", ResourceVersion: "42"})
+	now := time.Now()
+
+	testdata := []struct {
+		name string
+		key  string
+		at   time.Time
+		seen bool
+	}{
+		{name: "first", key: key, at: now},
+		{name: "other-webhook", key: key, at: now.Add(time.Second), seen: true},
+		{name: "recreated", key: deletionKey(recycledObj, &metav1.ObjectMeta{UID: "7c2d2a7f", ResourceVersion: "43"}), at: now.Add(time.Second)},
+		{name: "expired", key: key, at: now.Add(time.Minute * 2)},
+		{name: "without-uid", key: deletionKey(recycledObj, &metav1.ObjectMeta{}), at: now},
+		{name: "without-uid-again", key: deletionKey(recycledObj, &metav1.ObjectMeta{}), at: now},
+	}
+
+	deletions := &recentDeletions{TTL: time.Minute}
+	for _, td := range testdata {
+		if got := deletions.Seen(td.key, td.at); got != td.seen {
+			t.Errorf("✗ %s: expected %v, got %v", td.name, td.seen, got)
+		}
+	}
+}
+
+func TestRecentDeletionsForget(t *testing.T) {
+	recycledObj := &api.RecycledObject{Group: "apps", Resource: "deployments", Namespace: "dev", Name: "nginx"}
+	key := deletionKey(recycledObj, &metav1.ObjectMeta{UID: "6b1c1f6e", ResourceVersion: "42"})
+	now := time.Now()
+
+	deletions := &recentDeletions{TTL: time.Minute}
+	if deletions.Seen(key, now) {
+		t.Fatalf("✗ expected the first deletion not to be seen")
+	}
+	// the deletion is denied, so its retry is recycled again
+	deletions.Forget(key)
+	if deletions.Seen(key, now.Add(time.Second)) {
+		t.Errorf("✗ expected the retried deletion not to be seen")
+	}
+	if !deletions.Seen(key, now.Add(time.Second*2)) {
+		t.Errorf("✗ expected the recycled deletion to be seen")
+	}
+}
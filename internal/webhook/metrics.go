@@ -0,0 +1,79 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	dryRunSkippedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_dry_run_skipped_total",
+		Help: "Number of dry-run deletions that were not recycled.",
+	}, []string{"group_resource"})
+
+	duplicateDeletionsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_duplicate_deletions_total",
+		Help: "Number of deletions that were not recycled again by another webhook of the same configuration.",
+	}, []string{"group_resource"})
+
+	oversizedObjectsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_oversized_objects_total",
+		Help: "Number of deleted objects larger than the max object size, by overflow policy.",
+	}, []string{"group_resource", "overflow_policy"})
+
+	redactFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_redact_failures_total",
+		Help: "Number of deletions that were denied because the deleted object couldn't be redacted.",
+	}, []string{"group_resource"})
+
+	storageErrorsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_storage_errors_total",
+		Help: "Number of failed operations on the storage backend of recycled objects.",
+	}, []string{"backend", "operation"})
+
+	certificateExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "krb_webhook_certificate_expiry_timestamp_seconds",
+		Help: "Unix time the serving certificate of the webhook expires at.",
+	})
+
+	queueDepth = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "krb_webhook_queue_depth",
+		Help: "Number of deleted objects in the recycle queue waiting for their RecycleItem to be created.",
+	})
+
+	queueDeadLetters = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "krb_webhook_queue_dead_letters",
+		Help: "Number of deleted objects in the dead letters of the recycle queue, which are not retried.",
+	})
+
+	queueFailuresTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "krb_webhook_queue_failures_total",
+		Help: "Number of failed attempts to create RecycleItems from the recycle queue, by whether they are retried or dead lettered.",
+	}, []string{"result"})
+)
+
+func init() {
+	registry.MustRegister(dryRunSkippedTotal, duplicateDeletionsTotal, oversizedObjectsTotal, redactFailuresTotal, storageErrorsTotal, certificateExpiry,
+		queueDepth, queueDeadLetters, queueFailuresTotal)
+}
+
+// metricsHandler serves the webhook metrics in the Prometheus format.
+var metricsHandler = promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
@@ -0,0 +1,261 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/internal/encryption"
+	"github.com/ketches/kube-recycle-bin/internal/storage"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// OverflowPolicy decides what happens to deleted objects that are larger than
+// the max object size once encoded.
+type OverflowPolicy string
+
+const (
+	// OverflowReject rejects the deletion, so the object is never lost.
+	OverflowReject OverflowPolicy = "Reject"
+	// OverflowTruncate keeps only the type and metadata of the object.
+	OverflowTruncate OverflowPolicy = "Truncate"
+	// OverflowOffload stores the object in ConfigMaps in krb-system.
+	OverflowOffload OverflowPolicy = "Offload"
+)
+
+// ParseOverflowPolicy parses the overflow policy.
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch policy := OverflowPolicy(s); policy {
+	case OverflowReject, OverflowTruncate, OverflowOffload:
+		return policy, nil
+	}
+	return "", fmt.Errorf("invalid overflow policy %q, must be one of: Reject|Truncate|Offload", s)
+}
+
+// errObjectTooLarge is returned for objects too large to recycle with the
+// Reject overflow policy.
+var errObjectTooLarge = errors.New("object too large")
+
+// payloadOptions are the options of storing deleted objects.
+type payloadOptions struct {
+	// Encoding of the deleted objects.
+	Encoding string
+	// MaxObjectSize is the max size of an encoded object stored in a
+	// RecycleItem, zero for no limit.
+	MaxObjectSize int
+	// OverflowPolicy applies to objects larger than MaxObjectSize.
+	OverflowPolicy OverflowPolicy
+	// OverflowBackend stores the objects offloaded by the Offload overflow
+	// policy.
+	OverflowBackend storage.Backend
+	// Backend stores all recycled objects if set, regardless of their size.
+	Backend storage.Backend
+	// ReadToken authorizes reading payloads from the payloads endpoint.
+	ReadToken string
+	// DeleteToken authorizes deleting payloads from the payloads endpoint.
+	DeleteToken string
+	// Encryptor encrypts recycled objects of the configured kinds, nil if
+	// encryption is disabled.
+	Encryptor *encryption.Encryptor
+}
+
+var payload = payloadOptions{
+	Encoding:       api.EncodingGzip,
+	MaxObjectSize:  768 * 1024,
+	OverflowPolicy: OverflowOffload,
+}
+
+// encode encodes the plain JSON of the recycled object, applies the overflow
+// policy if it is too large and encrypts it if its kind is encrypted. It
+// returns the storage backend the object is offloaded to, if any.
+func (opts *payloadOptions) encode(recycledObj *api.RecycledObject) (storage.Backend, error) {
+	if err := recycledObj.SetData(recycledObj.Raw, opts.Encoding); err != nil {
+		return nil, err
+	}
+	backend, err := opts.overflow(recycledObj)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Encryptor != nil && opts.Encryptor.Encrypts(recycledObj.ObjectGroupKind()) {
+		if err := opts.Encryptor.Encrypt(context.Background(), recycledObj); err != nil {
+			return nil, fmt.Errorf("failed to encrypt: %w", err)
+		}
+	}
+	return backend, nil
+}
+
+// overflow returns the storage backend the encoded object is offloaded to,
+// applying the overflow policy if it is too large.
+func (opts *payloadOptions) overflow(recycledObj *api.RecycledObject) (storage.Backend, error) {
+	if opts.Backend != nil {
+		return opts.Backend, nil
+	}
+	size := len(recycledObj.Raw)
+	if opts.MaxObjectSize <= 0 || size <= opts.MaxObjectSize {
+		return nil, nil
+	}
+
+	oversizedObjectsTotal.WithLabelValues(recycledObj.GroupResource().String(), string(opts.OverflowPolicy)).Inc()
+	switch opts.OverflowPolicy {
+	case OverflowTruncate:
+		tlog.Warnf("» truncate deleted object [%s: %s] of %d bytes, only its metadata is kept", recycledObj.GroupResource().String(), recycledObj.Key(), size)
+		return nil, recycledObj.Truncate()
+	case OverflowOffload:
+		tlog.Infof("» offload deleted object [%s: %s] of %d bytes", recycledObj.GroupResource().String(), recycledObj.Key(), size)
+		return opts.OverflowBackend, nil
+	}
+	return nil, fmt.Errorf("%w: %d bytes exceed the max object size of %d bytes", errObjectTooLarge, size, opts.MaxObjectSize)
+}
+
+// setupEncryption sets up the encryption configured by the krb-encryption
+// Secret, creating it with a generated key if there is none.
+func (opts *payloadOptions) setupEncryption(ctx context.Context) {
+	config, err := encryption.EnsureConfig(ctx, kube.Client())
+	if err != nil {
+		tlog.Fatalf("✗ failed to load encryption config: %v", err)
+	}
+	if opts.Encryptor, err = encryption.NewEncryptor(config); err != nil {
+		tlog.Fatalf("✗ invalid encryption config: %v", err)
+	}
+	kinds := config.Kinds
+	if len(kinds) == 0 {
+		kinds = encryption.DefaultKinds
+	}
+	tlog.Infof("✓ recycled objects of kinds [%s] are encrypted.", strings.Join(kinds, ", "))
+}
+
+// setupStorage sets up the storage backend configured by the krb-storage
+// Secret, and the tokens of the payloads endpoint.
+func (opts *payloadOptions) setupStorage(ctx context.Context) {
+	opts.OverflowBackend = storage.NewConfigMapBackend(kube.Client())
+
+	config, err := storage.LoadConfig(ctx, kube.Client())
+	if err != nil {
+		tlog.Fatalf("✗ failed to load storage config: %v", err)
+	}
+	if opts.Backend, err = storage.New(config, kube.Client()); err != nil {
+		tlog.Fatalf("✗ invalid storage config: %v", err)
+	}
+	if opts.Backend == nil {
+		return
+	}
+	tlog.Infof("✓ recycled objects are stored in the %s backend.", opts.Backend.Name())
+
+	opts.ReadToken = ensurePayloadToken(ctx, consts.PayloadReaderSecretName)
+	opts.DeleteToken = ensurePayloadToken(ctx, consts.PayloadDeleterSecretName)
+}
+
+// ensurePayloadToken returns the token of the payloads endpoint in the
+// Secret, creating it with a generated token if there is none. Each token is
+// kept in its own Secret, apart from the storage credentials, so reading and
+// deleting payloads are granted separately by access to the Secrets.
+func ensurePayloadToken(ctx context.Context, secretName string) string {
+	secrets := kube.Client().CoreV1().Secrets(consts.WebhookNamespace)
+	secret, err := secrets.Get(ctx, secretName, metav1.GetOptions{})
+	if err != nil && !k8serrors.IsNotFound(err) {
+		tlog.Fatalf("✗ failed to get secret [%s]: %v", secretName, err)
+	}
+	if err == nil && len(secret.Data[consts.PayloadTokenSecretKey]) > 0 {
+		return string(secret.Data[consts.PayloadTokenSecretKey])
+	}
+
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		tlog.Fatalf("✗ failed to generate payload token: %v", err)
+	}
+	data := map[string][]byte{consts.PayloadTokenSecretKey: []byte(hex.EncodeToString(token))}
+	if err == nil {
+		secret.Data = data
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	} else {
+		_, err = secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: consts.WebhookNamespace},
+			Data:       data,
+		}, metav1.CreateOptions{})
+	}
+	if err != nil {
+		tlog.Fatalf("✗ failed to store payload token in secret [%s]: %v", secretName, err)
+	}
+	tlog.Infof("✓ payload token stored in secret [%s].", secretName)
+	return string(data[consts.PayloadTokenSecretKey])
+}
+
+// servePayloads webhook handler for reading and deleting the payloads stored
+// in the storage backend, used by krb-controller and krb-cli through the API
+// server proxy of the krb-webhook Service. Reading requires the read token and
+// deleting the delete token.
+func servePayloads(w http.ResponseWriter, r *http.Request) {
+	var token string
+	switch r.Method {
+	case http.MethodGet:
+		token = payload.ReadToken
+	case http.MethodDelete:
+		token = payload.DeleteToken
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if payload.Backend == nil || token == "" ||
+		subtle.ConstantTimeCompare([]byte(r.Header.Get(consts.PayloadTokenHeader)), []byte(token)) != 1 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+	if backend := r.URL.Query().Get("backend"); backend != payload.Backend.Name() {
+		http.Error(w, fmt.Sprintf("payloads are stored in the %s backend, not %s", payload.Backend.Name(), backend), http.StatusConflict)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, consts.WebhookPayloadsPath)
+	switch r.Method {
+	case http.MethodGet:
+		data, err := payload.Backend.Get(r.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			tlog.Errorf("✗ failed to get payload [%s]: %v", key, err)
+			storageErrorsTotal.WithLabelValues(payload.Backend.Name(), "get").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(data)
+	case http.MethodDelete:
+		if err := payload.Backend.Delete(r.Context(), key); err != nil {
+			tlog.Errorf("✗ failed to delete payload [%s]: %v", key, err)
+			storageErrorsTotal.WithLabelValues(payload.Backend.Name(), "delete").Inc()
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		tlog.Infof("✓ payload [%s] deleted.", key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/internal/encryption"
+	"github.com/ketches/kube-recycle-bin/internal/storage"
+)
+
+func TestPayloadEncode(t *testing.T) {
+	overflow, err := storage.NewFilesystemBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("✗ failed to create backend: %v", err)
+	}
+	encryptor, err := encryption.NewEncryptor(&encryption.Config{Kinds: []string{"ConfigMap"}, Key: make([]byte, 32)})
+	if err != nil {
+		t.Fatalf("✗ failed to create encryptor: %v", err)
+	}
+	raw := `{"apiVersion":"v1","kind":"ConfigMap","metadata":{"name":"app"},"data":{"key":"` + strings.Repeat("x", 4096) + `"}}`
+
+	testdata := []struct {
+		name      string
+		opts      payloadOptions
+		tooLarge  bool
+		truncated bool
+		offloaded bool
+		encrypted bool
+	}{
+		{
+			name: "fits-compressed",
+			opts: payloadOptions{Encoding: api.EncodingGzip, MaxObjectSize: 1024, OverflowPolicy: OverflowReject},
+		},
+		{
+			name:     "reject",
+			opts:     payloadOptions{Encoding: api.EncodingNone, MaxObjectSize: 1024, OverflowPolicy: OverflowReject},
+			tooLarge: true,
+		},
+		{
+			name:      "truncate",
+			opts:      payloadOptions{Encoding: api.EncodingNone, MaxObjectSize: 1024, OverflowPolicy: OverflowTruncate},
+			truncated: true,
+		},
+		{
+			name:      "offload",
+			opts:      payloadOptions{Encoding: api.EncodingNone, MaxObjectSize: 1024, OverflowPolicy: OverflowOffload, OverflowBackend: overflow},
+			offloaded: true,
+		},
+		{
+			name:      "backend",
+			opts:      payloadOptions{Encoding: api.EncodingGzip, MaxObjectSize: 1024 * 1024, OverflowPolicy: OverflowReject, Backend: overflow},
+			offloaded: true,
+		},
+		{
+			name:      "encrypt",
+			opts:      payloadOptions{Encoding: api.EncodingGzip, MaxObjectSize: 1024, OverflowPolicy: OverflowReject, Encryptor: encryptor},
+			encrypted: true,
+		},
+		{
+			name: "no-limit",
+			opts: payloadOptions{Encoding: api.EncodingNone, OverflowPolicy: OverflowReject},
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			obj := &api.RecycledObject{Kind: "ConfigMap", Resource: "configmaps", Name: "app", Raw: []byte(raw)}
+			backend, err := tt.opts.encode(obj)
+			if tooLarge := errors.Is(err, errObjectTooLarge); tooLarge != tt.tooLarge {
+				t.Fatalf("✗ expected too large %v, got error %v", tt.tooLarge, err)
+			}
+			if tt.tooLarge {
+				return
+			}
+			if err != nil {
+				t.Fatalf("✗ failed to encode: %v", err)
+			}
+			if obj.Truncated != tt.truncated {
+				t.Errorf("✗ expected truncated %v, got %v", tt.truncated, obj.Truncated)
+			}
+			if offloaded := backend != nil; offloaded != tt.offloaded {
+				t.Errorf("✗ expected offloaded %v, got %v", tt.offloaded, offloaded)
+			}
+			if encrypted := obj.Encryption != nil; encrypted != tt.encrypted {
+				t.Errorf("✗ expected encrypted %v, got %v", tt.encrypted, encrypted)
+			}
+			if err := encryptor.Decrypt(context.Background(), obj); err != nil {
+				t.Fatalf("✗ failed to decrypt: %v", err)
+			}
+			if data, err := obj.Data(); err != nil || (!tt.truncated && string(data) != raw) {
+				t.Errorf("✗ expected the object decoded, got %d bytes, %v", len(data), err)
+			}
+		})
+	}
+}
+
+func TestServePayloads(t *testing.T) {
+	backend, err := storage.NewFilesystemBackend(t.TempDir())
+	if err != nil {
+		t.Fatalf("✗ failed to create backend: %v", err)
+	}
+	saved := payload
+	defer func() { payload = saved }()
+	payload.Backend = backend
+	payload.ReadToken = "read"
+	payload.DeleteToken = "delete"
+
+	testdata := []struct {
+		name   string
+		method string
+		token  string
+		status int
+	}{
+		{name: "get", method: http.MethodGet, token: "read", status: http.StatusOK},
+		{name: "get with delete token", method: http.MethodGet, token: "delete", status: http.StatusForbidden},
+		{name: "delete with read token", method: http.MethodDelete, token: "read", status: http.StatusForbidden},
+		{name: "delete", method: http.MethodDelete, token: "delete", status: http.StatusNoContent},
+		{name: "without token", method: http.MethodGet, status: http.StatusForbidden},
+		{name: "put", method: http.MethodPut, token: "delete", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := backend.Put(context.Background(), "app-skk5c89b", []byte("payload")); err != nil {
+				t.Fatalf("✗ failed to put payload: %v", err)
+			}
+			r := httptest.NewRequest(tt.method, consts.WebhookPayloadsPath+"app-skk5c89b?backend="+backend.Name(), nil)
+			r.Header.Set(consts.PayloadTokenHeader, tt.token)
+			w := httptest.NewRecorder()
+			servePayloads(w, r)
+			if w.Code != tt.status {
+				t.Errorf("✗ expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"slices"
+	"strings"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// policies serves RecyclePolicies and Namespaces to the admission requests
+// from informers, instead of reading them from the API server on every
+// deletion.
+var policies client.Reader
+
+// setupPolicyCache starts the informers of RecyclePolicies and Namespaces and
+// waits until they are synced.
+func setupPolicyCache(ctx context.Context) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(api.AddToScheme(scheme))
+	c, err := cache.New(kube.RestConfig(), cache.Options{Scheme: scheme})
+	if err != nil {
+		tlog.Fatalf("✗ failed to create RecyclePolicy cache: %v", err)
+	}
+	for _, obj := range []client.Object{&api.RecyclePolicy{}, &corev1.Namespace{}} {
+		if _, err := c.GetInformer(ctx, obj); err != nil {
+			tlog.Fatalf("✗ failed to create informer of %T: %v", obj, err)
+		}
+	}
+	go func() {
+		if err := c.Start(ctx); err != nil {
+			tlog.Fatalf("✗ failed to start RecyclePolicy cache: %v", err)
+		}
+	}()
+	if !c.WaitForCacheSync(ctx) {
+		tlog.Fatalf("✗ failed to sync RecyclePolicy cache")
+	}
+	policies = c
+}
+
+// matchRecyclePolicy returns the RecyclePolicy that applies to the recycled
+// object. When several policies match, the first one by name wins.
+func matchRecyclePolicy(ctx context.Context, reader client.Reader, recycledObj *api.RecycledObject, objectMeta *metav1.ObjectMeta) (*api.RecyclePolicy, error) {
+	var list api.RecyclePolicyList
+	if err := reader.List(ctx, &list); err != nil {
+		return nil, err
+	}
+
+	// Namespace labels are only needed by policies with a namespace selector.
+	var namespaceLabels labels.Set
+	if recycledObj.Namespace != "" && slices.ContainsFunc(list.Items, func(policy api.RecyclePolicy) bool {
+		return policy.Target.NamespaceSelector != nil
+	}) {
+		var ns corev1.Namespace
+		if err := reader.Get(ctx, client.ObjectKey{Name: recycledObj.Namespace}, &ns); err != nil {
+			return nil, err
+		}
+		namespaceLabels = ns.Labels
+	}
+
+	// Categories of the resource are only needed by policies with a category.
+	var categories []string
+	if slices.ContainsFunc(list.Items, func(policy api.RecyclePolicy) bool {
+		return policy.Target.Category != ""
+	}) {
+		resource, err := kube.GetAPIResource(recycledObj.GroupVersionResource())
+		if err != nil {
+			return nil, err
+		}
+		categories = resource.Categories
+	}
+
+	var matched []api.RecyclePolicy
+	for _, policy := range list.Items {
+		if policy.Matches(recycledObj.GroupResource(), categories, recycledObj.Namespace, objectMeta.Labels, namespaceLabels) {
+			matched = append(matched, policy)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, nil
+	}
+
+	slices.SortFunc(matched, func(a, b api.RecyclePolicy) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return &matched[0], nil
+}
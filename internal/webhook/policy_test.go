@@ -0,0 +1,76 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMatchRecyclePolicy(t *testing.T) {
+	scheme := runtime.NewScheme()
+	utilruntime.Must(corev1.AddToScheme(scheme))
+	utilruntime.Must(api.AddToScheme(scheme))
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "dev", Labels: map[string]string{"env": "dev"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "prod", Labels: map[string]string{"env": "prod"}}},
+		&api.RecyclePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "b-deployments"},
+			Target:     api.RecycleTarget{Group: "apps", Resource: "deployments", Namespaces: []string{"*"}},
+		},
+		&api.RecyclePolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "a-dev-deployments"},
+			Target: api.RecycleTarget{Group: "apps", Resource: "deployments", Namespaces: []string{"*"},
+				NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"env": "dev"}}},
+		},
+	).Build()
+
+	testdata := []struct {
+		name      string
+		resource  string
+		namespace string
+		expected  string
+	}{
+		{name: "namespace-selector", resource: "deployments", namespace: "dev", expected: "a-dev-deployments"},
+		{name: "fallback", resource: "deployments", namespace: "prod", expected: "b-deployments"},
+		{name: "no-match", resource: "statefulsets", namespace: "dev"},
+	}
+
+	for _, td := range testdata {
+		t.Run(td.name, func(t *testing.T) {
+			recycledObj := &api.RecycledObject{Group: "apps", Version: "v1", Resource: td.resource, Namespace: td.namespace, Name: "nginx"}
+			policy, err := matchRecyclePolicy(context.Background(), reader, recycledObj, &metav1.ObjectMeta{})
+			if err != nil {
+				t.Fatalf("✗ failed to match recycle policy: %v", err)
+			}
+			var got string
+			if policy != nil {
+				got = policy.Name
+			}
+			if got != td.expected {
+				t.Errorf("✗ expected %q, got %q", td.expected, got)
+			}
+		})
+	}
+}
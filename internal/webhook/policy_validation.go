@@ -0,0 +1,194 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/kube"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	admissionv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// namespaceChecker reports whether the namespace exists.
+type namespaceChecker func(namespace string) (bool, error)
+
+// validateRecyclePolicies webhook handler for validating created and updated
+// RecyclePolicies.
+func validateRecyclePolicies(w http.ResponseWriter, r *http.Request) {
+	tlog.Infof("» received request: %s", r.URL.Path)
+
+	review, err := parseRequest(r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("✗ failed to parse request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	request := review.Request
+	policy := &api.RecyclePolicy{}
+	if err := json.Unmarshal(request.Object.Raw, policy); err != nil {
+		validationResponse(w, review, nil, fmt.Errorf("failed to decode RecyclePolicy: %w", err))
+		return
+	}
+
+	// Policies being deleted and updates that leave the spec alone, such as
+	// finalizer changes by krb-controller, must never be blocked.
+	if !policy.DeletionTimestamp.IsZero() {
+		response(w, review)
+		return
+	}
+	if request.Operation == admissionv1.Update {
+		oldPolicy := &api.RecyclePolicy{}
+		if err := json.Unmarshal(request.OldObject.Raw, oldPolicy); err == nil && !specChanged(oldPolicy, policy) {
+			response(w, review)
+			return
+		}
+	}
+
+	discovered, err := kube.GetPreferredAPIResources()
+	if err != nil {
+		validationResponse(w, review, nil, fmt.Errorf("failed to discover resources: %w", err))
+		return
+	}
+	warnings, err := validateRecyclePolicy(policy, discovered, namespaceExists)
+	if err != nil {
+		tlog.Infof("» reject RecyclePolicy [%s]: %v", policy.Name, err)
+	}
+	validationResponse(w, review, warnings, err)
+}
+
+// specChanged reports whether the validated fields of the RecyclePolicy
+// changed.
+func specChanged(oldPolicy, policy *api.RecyclePolicy) bool {
+	return !equality.Semantic.DeepEqual(
+		[]any{oldPolicy.Target, oldPolicy.Retention, oldPolicy.DeletionPolicy, oldPolicy.Webhook, oldPolicy.Redact},
+		[]any{policy.Target, policy.Retention, policy.DeletionPolicy, policy.Webhook, policy.Redact},
+	)
+}
+
+// validateRecyclePolicy rejects RecyclePolicies without target resources, or
+// whose target resources are excluded, unknown among the discovered
+// resources, can't be deleted, or are cluster-scoped while the target lists
+// namespaces. Target namespaces that don't exist are returned as warnings.
+func validateRecyclePolicy(policy *api.RecyclePolicy, discovered []metav1.APIResource, namespaceExists namespaceChecker) ([]string, error) {
+	target := &policy.Target
+	if len(target.TargetResources()) == 0 && target.Category == "" {
+		return nil, fmt.Errorf("target has no resources, one of resource, resources or category is required")
+	}
+	if err := exclusions.Validate(target); err != nil {
+		return nil, err
+	}
+	if policy.Webhook != nil {
+		if err := policy.Webhook.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	for _, rule := range policy.Redact {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if policy.Retention != nil && policy.Retention.Duration < 0 {
+		return nil, fmt.Errorf("invalid retention %s, must not be negative", policy.Retention.Duration)
+	}
+
+	for _, tr := range target.TargetResources() {
+		gr := tr.GroupResource()
+		if tr.IsWildcard() {
+			// a "*" resource must resolve to at least one resource of the group
+			wildcard := api.RecycleTarget{Group: tr.Group, Resource: tr.Resource, Namespaces: target.Namespaces, NamespaceSelector: target.NamespaceSelector}
+			if len(wildcard.Resolve(discovered, &exclusions)) == 0 {
+				return nil, fmt.Errorf("no resources can be recycled for target resource %s", gr.String())
+			}
+			continue
+		}
+
+		index := slices.IndexFunc(discovered, func(resource metav1.APIResource) bool {
+			return resource.Group == gr.Group && resource.Name == gr.Resource
+		})
+		if index < 0 {
+			return nil, fmt.Errorf("unknown target resource %s", gr.String())
+		}
+		if !slices.Contains(discovered[index].Verbs, "delete") {
+			return nil, fmt.Errorf("target resource %s can't be deleted", gr.String())
+		}
+		if !discovered[index].Namespaced && target.LimitedToNamespaces() {
+			return nil, fmt.Errorf("target resource %s is cluster-scoped, it can't be limited to namespaces", gr.String())
+		}
+	}
+	if target.Category != "" {
+		category := api.RecycleTarget{Category: target.Category, Namespaces: target.Namespaces, NamespaceSelector: target.NamespaceSelector}
+		if len(category.Resolve(discovered, &exclusions)) == 0 {
+			return nil, fmt.Errorf("no resources can be recycled for target category %s", target.Category)
+		}
+	}
+
+	var warnings []string
+	for _, namespace := range target.Namespaces {
+		if namespace == metav1.NamespaceAll || namespace == "*" {
+			continue
+		}
+		exists, err := namespaceExists(namespace)
+		if err != nil {
+			tlog.Warnf("✗ failed to check namespace [%s]: %v", namespace, err)
+			continue
+		}
+		if !exists {
+			warnings = append(warnings, fmt.Sprintf("target namespace %s does not exist", namespace))
+		}
+	}
+	return warnings, nil
+}
+
+func namespaceExists(namespace string) (bool, error) {
+	_, err := kube.Client().CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if k8serrors.IsNotFound(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// validationResponse sends the response to the admission webhook, denying the
+// request if err is not nil.
+func validationResponse(w http.ResponseWriter, request *admissionv1.AdmissionReview, warnings []string, err error) {
+	response := &admissionv1.AdmissionReview{
+		TypeMeta: request.TypeMeta,
+		Response: &admissionv1.AdmissionResponse{
+			UID:      request.Request.UID,
+			Allowed:  err == nil,
+			Warnings: warnings,
+		},
+	}
+	if err != nil {
+		response.Response.Result = &metav1.Status{
+			Status:  metav1.StatusFailure,
+			Code:    http.StatusUnprocessableEntity,
+			Reason:  metav1.StatusReasonInvalid,
+			Message: err.Error(),
+		}
+	}
+
+	encodeResponse(w, response)
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateRecyclePolicy(t *testing.T) {
+	discovered := []metav1.APIResource{
+		{Group: "apps", Name: "deployments", Namespaced: true, Verbs: []string{"get", "list", "delete"}, Categories: []string{"all"}},
+		{Group: "apps", Name: "deployments/scale", Namespaced: true, Verbs: []string{"get", "update"}},
+		{Name: "namespaces", Verbs: []string{"get", "list", "delete"}},
+		{Name: "bindings", Namespaced: true, Verbs: []string{"create"}},
+		{Group: "rbac.authorization.k8s.io", Name: "clusterroles", Verbs: []string{"get", "list", "delete"}},
+	}
+	namespaceExists := func(namespace string) (bool, error) {
+		return namespace == "dev", nil
+	}
+
+	testdata := []struct {
+		name     string
+		target   api.RecycleTarget
+		redact   []api.RedactRule
+		warnings []string
+		invalid  bool
+	}{
+		{
+			name:   "valid",
+			target: api.RecycleTarget{Group: "apps", Resource: "deployments", Namespaces: []string{"dev"}},
+		},
+		{
+			name:     "missing-namespace",
+			target:   api.RecycleTarget{Group: "apps", Resource: "deployments", Namespaces: []string{"dev", "prod"}},
+			warnings: []string{"target namespace prod does not exist"},
+		},
+		{
+			name:    "typo",
+			target:  api.RecycleTarget{Group: "apps", Resource: "deploymnets"},
+			invalid: true,
+		},
+		{
+			name:    "not-deletable",
+			target:  api.RecycleTarget{Resource: "bindings"},
+			invalid: true,
+		},
+		{
+			name:   "cluster-scoped",
+			target: api.RecycleTarget{Resource: "namespaces", Namespaces: []string{"*"}},
+		},
+		{
+			name:    "cluster-scoped-with-namespaces",
+			target:  api.RecycleTarget{Resource: "namespaces", Namespaces: []string{"dev"}},
+			invalid: true,
+		},
+		{
+			name:   "all-in-group",
+			target: api.RecycleTarget{Group: "apps", Resource: "*", Namespaces: []string{"dev"}},
+		},
+		{
+			name:    "all-in-group-cluster-scoped-only",
+			target:  api.RecycleTarget{Group: "rbac.authorization.k8s.io", Resource: "*", Namespaces: []string{"dev"}},
+			invalid: true,
+		},
+		{
+			name:   "category",
+			target: api.RecycleTarget{Category: "all"},
+		},
+		{
+			name:    "unknown-category",
+			target:  api.RecycleTarget{Category: "alll"},
+			invalid: true,
+		},
+		{
+			name:    "unknown-resource-in-list",
+			target:  api.RecycleTarget{Resources: []api.TargetResource{{Group: "apps", Resource: "deployments"}, {Resource: "servics"}}},
+			invalid: true,
+		},
+		{
+			name:    "no-resources",
+			target:  api.RecycleTarget{Namespaces: []string{"dev"}},
+			invalid: true,
+		},
+		{
+			name:    "excluded",
+			target:  api.RecycleTarget{Group: api.Group, Resource: "recycleitems"},
+			invalid: true,
+		},
+		{
+			name:   "redact",
+			target: api.RecycleTarget{Group: "apps", Resource: "deployments"},
+			redact: []api.RedactRule{{Group: "apps", Kind: "Deployment", Fields: []string{"$.spec.template.spec.containers[*].env[?(@.name=='TOKEN')].value"}}},
+		},
+		{
+			name:    "invalid-redact",
+			target:  api.RecycleTarget{Group: "apps", Resource: "deployments"},
+			redact:  []api.RedactRule{{Group: "apps", Kind: "Deployment", Fields: []string{"$.data['key'"}}},
+			invalid: true,
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			warnings, err := validateRecyclePolicy(&api.RecyclePolicy{Target: tt.target, Redact: tt.redact}, discovered, namespaceExists)
+			if invalid := err != nil; invalid != tt.invalid {
+				t.Errorf("✗ expected invalid %v, got error %v", tt.invalid, err)
+			}
+			if !reflect.DeepEqual(warnings, tt.warnings) {
+				t.Errorf("✗ expected warnings %v, got %v", tt.warnings, warnings)
+			}
+		})
+	}
+}
+
+func TestSpecChanged(t *testing.T) {
+	oldPolicy := &api.RecyclePolicy{
+		Target: api.RecycleTarget{Group: "apps", Resource: "deployments"},
+		Redact: []api.RedactRule{{Group: "apps", Fields: []string{"spec.replicas"}}},
+	}
+	testdata := []struct {
+		name    string
+		update  func(policy *api.RecyclePolicy)
+		changed bool
+	}{
+		{name: "labels", update: func(policy *api.RecyclePolicy) { policy.Labels = map[string]string{"foo": "bar"} }},
+		{name: "target", update: func(policy *api.RecyclePolicy) { policy.Target.Resource = "statefulsets" }, changed: true},
+		{name: "redact", update: func(policy *api.RecyclePolicy) { policy.Redact[0].Fields = []string{"$."} }, changed: true},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := oldPolicy.DeepCopy()
+			tt.update(policy)
+			if changed := specChanged(oldPolicy, policy); changed != tt.changed {
+				t.Errorf("✗ expected changed %v, got %v", tt.changed, changed)
+			}
+		})
+	}
+}
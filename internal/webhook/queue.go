@@ -0,0 +1,350 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	"github.com/ketches/kube-recycle-bin/pkg/tlog"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// deadLetterDir is the directory in the spool directory entries are moved to
+// once they can't be recycled.
+const deadLetterDir = "dead"
+
+// recycleQueue creates RecycleItems asynchronously from entries persisted in
+// a spool directory, so a deleted object isn't lost when the API server is
+// unavailable or throttles a mass deletion, or the webhook restarts as long
+// as the spool directory outlives the pod. Failed entries are retried with
+// exponential backoff, and moved to the dead letter directory after the max
+// attempts or a permanent error. Dead letters keep referencing the payloads
+// stored for them, so they can be retried by moving them back to the spool
+// directory, or are discarded together with their payloads on start.
+type recycleQueue struct {
+	Dir         string
+	Workers     int
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// Create creates the RecycleItem of an entry once, failed attempts are
+	// retried by the queue.
+	Create func(ctx context.Context, recycleItem *api.RecycleItem) error
+	// DiscardDeadLetters discards the dead letters on start instead of
+	// keeping them.
+	DiscardDeadLetters bool
+	// Discard deletes the payload stored for the RecycleItem of a discarded
+	// dead letter, if any.
+	Discard func(ctx context.Context, recycleItem *api.RecycleItem) error
+
+	queue   workqueue.TypedDelayingInterface[string]
+	pending atomic.Int64
+	// recovered are the entries left in the spool directory by an earlier
+	// run, whose RecycleItems may have been created before it stopped.
+	recovered map[string]bool
+}
+
+// queueEntry is a RecycleItem to create, persisted as a JSON file in the
+// spool directory.
+type queueEntry struct {
+	RecycleItem *api.RecycleItem `json:"recycleItem"`
+	EnqueuedAt  time.Time        `json:"enqueuedAt"`
+	Attempts    int              `json:"attempts,omitempty"`
+	LastError   string           `json:"lastError,omitempty"`
+}
+
+// Start loads the entries left in the spool directory and processes entries
+// with the workers until the queue is stopped.
+func (q *recycleQueue) Start(ctx context.Context) error {
+	if err := os.MkdirAll(filepath.Join(q.Dir, deadLetterDir), 0o700); err != nil {
+		return fmt.Errorf("failed to create spool directory [%s]: %w", q.Dir, err)
+	}
+	q.queue = workqueue.NewTypedDelayingQueue[string]()
+
+	files, err := q.list("")
+	if err != nil {
+		return err
+	}
+	q.recovered = make(map[string]bool, len(files))
+	for _, file := range files {
+		q.recovered[file] = true
+		q.pending.Add(1)
+		q.queue.Add(file)
+	}
+	queueDepth.Set(float64(q.pending.Load()))
+	if q.DiscardDeadLetters {
+		if err := q.discardDeadLetters(ctx); err != nil {
+			return err
+		}
+	}
+	deadLetters, err := q.list(deadLetterDir)
+	if err != nil {
+		return err
+	}
+	queueDeadLetters.Set(float64(len(deadLetters)))
+	if len(files) > 0 || len(deadLetters) > 0 {
+		tlog.Infof("» recycle queue has %d pending entries and %d dead letters.", len(files), len(deadLetters))
+	}
+
+	for range q.Workers {
+		go func() {
+			for q.processNext(ctx) {
+			}
+		}()
+	}
+	return nil
+}
+
+// Stop stops queueing entries and waits for the workers to process the
+// queued ones until the context is done. Entries waiting to be retried, or
+// still queued when the context is done, stay in the spool directory and are
+// processed on the next start.
+func (q *recycleQueue) Stop(ctx context.Context) {
+	drained := make(chan struct{})
+	go func() {
+		// the queue only drains the entries being processed, so the queued
+		// ones are waited for first
+		q.queue.ShutDown()
+		for q.queue.Len() > 0 && ctx.Err() == nil {
+			time.Sleep(time.Millisecond * 100)
+		}
+		q.queue.ShutDownWithDrain()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		tlog.Info("✓ drain recycle queue done.")
+	case <-ctx.Done():
+		tlog.Warnf("✗ failed to drain recycle queue, %d entries left in the spool directory: %v", q.pending.Load(), ctx.Err())
+	}
+}
+
+// Enqueue persists the RecycleItem in the spool directory and queues it, it
+// is recycled once Enqueue returns without error.
+func (q *recycleQueue) Enqueue(recycleItem *api.RecycleItem) error {
+	file := fmt.Sprintf("%d-%s.json", time.Now().UnixNano(), recycleItem.Name)
+	if err := q.write(file, &queueEntry{RecycleItem: recycleItem, EnqueuedAt: time.Now()}); err != nil {
+		return err
+	}
+	queueDepth.Set(float64(q.pending.Add(1)))
+	q.queue.Add(file)
+	return nil
+}
+
+func (q *recycleQueue) processNext(ctx context.Context) bool {
+	file, shutdown := q.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer q.queue.Done(file)
+
+	entry, err := q.read(file)
+	if err != nil {
+		tlog.Errorf("✗ failed to read recycle queue entry [%s]: %v", file, err)
+		q.deadLetter(file, nil)
+		return true
+	}
+
+	err = q.Create(ctx, entry.RecycleItem)
+	if k8serrors.IsAlreadyExists(err) && (entry.Attempts > 0 || q.recovered[file]) {
+		// created by an earlier attempt whose response was lost, or before
+		// the entry was removed by an earlier run
+		err = nil
+	}
+	if err == nil {
+		q.remove(file)
+		return true
+	}
+
+	entry.Attempts++
+	entry.LastError = err.Error()
+	// a RecycleItem of the same name created by someone else is a name
+	// collision, which won't go away by retrying with the same name
+	if entry.Attempts >= q.MaxAttempts || isPermanent(err) || k8serrors.IsAlreadyExists(err) {
+		tlog.Errorf("✗ failed to recycle deleted object [%s: %s] after %d attempts, moved to dead letters: %v",
+			entry.RecycleItem.Object.GroupResource().String(), entry.RecycleItem.Object.Key(), entry.Attempts, err)
+		q.deadLetter(file, entry)
+		return true
+	}
+
+	backoff := q.backoff(entry.Attempts)
+	tlog.Warnf("✗ failed to recycle deleted object [%s: %s], retrying in %s: %v",
+		entry.RecycleItem.Object.GroupResource().String(), entry.RecycleItem.Object.Key(), backoff, err)
+	queueFailuresTotal.WithLabelValues("retry").Inc()
+	if err := q.write(file, entry); err != nil {
+		tlog.Errorf("✗ failed to update recycle queue entry [%s]: %v", file, err)
+	}
+	q.queue.AddAfter(file, backoff)
+	return true
+}
+
+// backoff returns the delay before the next attempt, doubling with every
+// failed attempt up to the max backoff.
+func (q *recycleQueue) backoff(attempts int) time.Duration {
+	backoff := q.Backoff
+	for range attempts - 1 {
+		if backoff *= 2; backoff >= q.MaxBackoff {
+			return q.MaxBackoff
+		}
+	}
+	return backoff
+}
+
+// isPermanent reports whether the error won't go away by retrying.
+func isPermanent(err error) bool {
+	return k8serrors.IsInvalid(err) || k8serrors.IsBadRequest(err) || k8serrors.IsRequestEntityTooLargeError(err)
+}
+
+func (q *recycleQueue) remove(file string) {
+	if err := os.Remove(filepath.Join(q.Dir, file)); err != nil && !os.IsNotExist(err) {
+		tlog.Errorf("✗ failed to remove recycle queue entry [%s]: %v", file, err)
+	}
+	queueDepth.Set(float64(q.pending.Add(-1)))
+}
+
+// deadLetter moves the entry to the dead letter directory, updated with the
+// failed attempts if it isn't nil. The payload stored for the entry is kept
+// for it to be retried, until the dead letter is discarded.
+func (q *recycleQueue) deadLetter(file string, entry *queueEntry) {
+	if entry != nil {
+		if err := q.write(file, entry); err != nil {
+			tlog.Errorf("✗ failed to update recycle queue entry [%s]: %v", file, err)
+		}
+		if ref := entry.RecycleItem.PayloadRef(); ref != nil {
+			tlog.Warnf("» dead letter [%s] keeps its payload [%s] in the %s backend until it is discarded.", file, ref.Key, ref.Backend)
+		}
+	}
+	if err := os.Rename(filepath.Join(q.Dir, file), filepath.Join(q.Dir, deadLetterDir, file)); err != nil {
+		tlog.Errorf("✗ failed to move recycle queue entry [%s] to dead letters: %v", file, err)
+	} else if err := syncDir(filepath.Join(q.Dir, deadLetterDir)); err != nil {
+		tlog.Errorf("✗ failed to sync dead letters: %v", err)
+	}
+	queueFailuresTotal.WithLabelValues("dead_letter").Inc()
+	queueDeadLetters.Inc()
+	queueDepth.Set(float64(q.pending.Add(-1)))
+}
+
+// discardDeadLetters removes the dead letters along with their stored
+// payloads. Dead letters whose payload can't be deleted are kept, so it isn't
+// left behind without a reference.
+func (q *recycleQueue) discardDeadLetters(ctx context.Context) error {
+	files, err := q.list(deadLetterDir)
+	if err != nil {
+		return err
+	}
+	discarded := 0
+	for _, file := range files {
+		file = filepath.Join(deadLetterDir, file)
+		entry, err := q.read(file)
+		if err == nil && q.Discard != nil {
+			if err := q.Discard(ctx, entry.RecycleItem); err != nil {
+				tlog.Errorf("✗ failed to discard payload of dead letter [%s], kept: %v", file, err)
+				continue
+			}
+		}
+		if err := os.Remove(filepath.Join(q.Dir, file)); err != nil && !os.IsNotExist(err) {
+			tlog.Errorf("✗ failed to discard dead letter [%s]: %v", file, err)
+			continue
+		}
+		discarded++
+	}
+	if discarded > 0 {
+		tlog.Infof("✓ discard %d dead letters done.", discarded)
+	}
+	return nil
+}
+
+// list returns the entries in the subdirectory of the spool directory, in the
+// order they were queued.
+func (q *recycleQueue) list(subdir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(filepath.Join(q.Dir, subdir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory [%s]: %w", q.Dir, err)
+	}
+	var files []string
+	for _, dirEntry := range dirEntries {
+		if dirEntry.Type().IsRegular() && strings.HasSuffix(dirEntry.Name(), ".json") {
+			files = append(files, dirEntry.Name())
+		}
+	}
+	slices.Sort(files)
+	return files, nil
+}
+
+func (q *recycleQueue) read(file string) (*queueEntry, error) {
+	data, err := os.ReadFile(filepath.Join(q.Dir, file))
+	if err != nil {
+		return nil, err
+	}
+	entry := &queueEntry{}
+	if err := json.Unmarshal(data, entry); err != nil {
+		return nil, err
+	}
+	if entry.RecycleItem == nil {
+		return nil, fmt.Errorf("entry has no RecycleItem")
+	}
+	return entry, nil
+}
+
+// write persists the entry to a temporary file first, which is synced and
+// renamed into the synced spool directory, so entries are complete and
+// present after a crash.
+func (q *recycleQueue) write(file string, entry *queueEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(q.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(q.Dir, file)); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(filepath.Join(q.Dir, file)))
+}
+
+// syncDir syncs the directory, so renames into it survive a crash.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,269 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package webhook
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ketches/kube-recycle-bin/internal/api"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func TestRecycleQueue(t *testing.T) {
+	transient := k8serrors.NewTooManyRequests("throttled", 1)
+	permanent := k8serrors.NewInvalid(schema.GroupKind{Group: api.Group, Kind: api.RecycleItemKind}, "foo", nil)
+
+	testdata := []struct {
+		name     string
+		errors   []error
+		attempts int
+		dead     bool
+	}{
+		{name: "created", attempts: 1},
+		{name: "transient", errors: []error{transient, transient}, attempts: 3},
+		{name: "created by lost attempt", errors: []error{transient, k8serrors.NewAlreadyExists(schema.GroupResource{}, "foo")}, attempts: 2},
+		{name: "max attempts", errors: []error{transient, transient, transient}, attempts: 3, dead: true},
+		{name: "permanent", errors: []error{permanent}, attempts: 1, dead: true},
+		{name: "name collision", errors: []error{k8serrors.NewAlreadyExists(schema.GroupResource{}, "foo")}, attempts: 1, dead: true},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			attempts := 0
+			done := make(chan struct{}, 1)
+			q := &recycleQueue{
+				Dir:         t.TempDir(),
+				Workers:     2,
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond * 10,
+				Create: func(ctx context.Context, recycleItem *api.RecycleItem) error {
+					mu.Lock()
+					defer mu.Unlock()
+					attempts++
+					var err error
+					if attempts <= len(tt.errors) {
+						err = tt.errors[attempts-1]
+					}
+					if err == nil || attempts == tt.attempts {
+						done <- struct{}{}
+					}
+					return err
+				},
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if err := q.Start(ctx); err != nil {
+				t.Fatalf("✗ failed to start queue: %v", err)
+			}
+			if err := q.Enqueue(newQueueTestItem("foo")); err != nil {
+				t.Fatalf("✗ failed to enqueue: %v", err)
+			}
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("✗ timed out waiting for attempts")
+			}
+			waitFor(t, func() bool { return q.pending.Load() == 0 })
+
+			mu.Lock()
+			if attempts != tt.attempts {
+				t.Errorf("✗ expected %d attempts, got %d", tt.attempts, attempts)
+			}
+			mu.Unlock()
+			if files, _ := q.list(""); len(files) != 0 {
+				t.Errorf("✗ expected no pending entries, got %v", files)
+			}
+			if deadLetters, _ := q.list(deadLetterDir); (len(deadLetters) == 1) != tt.dead {
+				t.Errorf("✗ expected dead letter %v, got %v", tt.dead, deadLetters)
+			}
+		})
+	}
+}
+
+func TestRecycleQueueRestart(t *testing.T) {
+	dir := t.TempDir()
+	// entries persisted before a restart, the first one failed once and the
+	// RecycleItem of the second one was created before it was removed
+	q := &recycleQueue{Dir: dir}
+	if err := os.MkdirAll(filepath.Join(dir, deadLetterDir), 0o700); err != nil {
+		t.Fatalf("✗ failed to create spool dir: %v", err)
+	}
+	_ = q.write("1-foo.json", &queueEntry{RecycleItem: newQueueTestItem("foo"), Attempts: 1, LastError: "throttled"})
+	_ = q.write("2-bar.json", &queueEntry{RecycleItem: newQueueTestItem("bar")})
+	_ = os.WriteFile(filepath.Join(dir, "3-broken.json"), []byte("{"), 0o600)
+
+	var mu sync.Mutex
+	var created []string
+	q = &recycleQueue{
+		Dir:         dir,
+		Workers:     1,
+		MaxAttempts: 3,
+		Backoff:     time.Millisecond,
+		MaxBackoff:  time.Millisecond,
+		Create: func(ctx context.Context, recycleItem *api.RecycleItem) error {
+			mu.Lock()
+			defer mu.Unlock()
+			created = append(created, recycleItem.Object.Name)
+			if recycleItem.Object.Name == "bar" {
+				return k8serrors.NewAlreadyExists(schema.GroupResource{}, recycleItem.Name)
+			}
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("✗ failed to start queue: %v", err)
+	}
+	waitFor(t, func() bool { return q.pending.Load() == 0 })
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(created) != 2 || created[0] != "foo" || created[1] != "bar" {
+		t.Errorf("✗ expected foo and bar to be created in order, got %v", created)
+	}
+	if deadLetters, _ := q.list(deadLetterDir); len(deadLetters) != 1 || deadLetters[0] != "3-broken.json" {
+		t.Errorf("✗ expected the broken entry in dead letters, got %v", deadLetters)
+	}
+}
+
+func TestRecycleQueueDiscardDeadLetters(t *testing.T) {
+	dir := t.TempDir()
+	q := &recycleQueue{Dir: dir}
+	if err := os.MkdirAll(filepath.Join(dir, deadLetterDir), 0o700); err != nil {
+		t.Fatalf("✗ failed to create spool dir: %v", err)
+	}
+	stored := newQueueTestItem("foo")
+	stored.Object.Ref = &api.PayloadRef{Backend: "configmap", Key: stored.Name}
+	failed := newQueueTestItem("bar")
+	failed.Object.Ref = &api.PayloadRef{Backend: "s3", Key: failed.Name}
+	_ = q.write(filepath.Join(deadLetterDir, "1-foo.json"), &queueEntry{RecycleItem: stored, Attempts: 3})
+	_ = q.write(filepath.Join(deadLetterDir, "2-bar.json"), &queueEntry{RecycleItem: failed, Attempts: 3})
+	_ = q.write(filepath.Join(deadLetterDir, "3-baz.json"), &queueEntry{RecycleItem: newQueueTestItem("baz"), Attempts: 3})
+	_ = os.WriteFile(filepath.Join(dir, deadLetterDir, "4-broken.json"), []byte("{"), 0o600)
+
+	var discarded []string
+	q = &recycleQueue{
+		Dir:                dir,
+		Workers:            1,
+		MaxAttempts:        3,
+		Backoff:            time.Millisecond,
+		MaxBackoff:         time.Millisecond,
+		DiscardDeadLetters: true,
+		Discard: func(ctx context.Context, recycleItem *api.RecycleItem) error {
+			if ref := recycleItem.PayloadRef(); ref != nil && ref.Backend == "s3" {
+				return errors.New("unavailable")
+			}
+			discarded = append(discarded, recycleItem.Object.Name)
+			return nil
+		},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := q.Start(ctx); err != nil {
+		t.Fatalf("✗ failed to start queue: %v", err)
+	}
+
+	if len(discarded) != 2 || discarded[0] != "foo" || discarded[1] != "baz" {
+		t.Errorf("✗ expected foo and baz to be discarded, got %v", discarded)
+	}
+	// the dead letter whose payload can't be deleted keeps referencing it
+	if deadLetters, _ := q.list(deadLetterDir); len(deadLetters) != 1 || deadLetters[0] != "2-bar.json" {
+		t.Errorf("✗ expected only the dead letter of bar to be kept, got %v", deadLetters)
+	}
+}
+
+func TestRecycleQueueStop(t *testing.T) {
+	testdata := []struct {
+		name    string
+		block   bool
+		pending int
+	}{
+		{name: "drained"},
+		{name: "timed out", block: true, pending: 2},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			unblock := make(chan struct{})
+			defer close(unblock)
+			q := &recycleQueue{
+				Dir:         t.TempDir(),
+				Workers:     1,
+				MaxAttempts: 3,
+				Backoff:     time.Millisecond,
+				MaxBackoff:  time.Millisecond,
+				Create: func(ctx context.Context, recycleItem *api.RecycleItem) error {
+					if tt.block {
+						<-unblock
+					}
+					return nil
+				},
+			}
+			if err := q.Start(context.Background()); err != nil {
+				t.Fatalf("✗ failed to start queue: %v", err)
+			}
+			for _, name := range []string{"foo", "bar"} {
+				if err := q.Enqueue(newQueueTestItem(name)); err != nil {
+					t.Fatalf("✗ failed to enqueue: %v", err)
+				}
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*100)
+			defer cancel()
+			q.Stop(ctx)
+			if files, _ := q.list(""); len(files) != tt.pending {
+				t.Errorf("✗ expected %d pending entries, got %v", tt.pending, files)
+			}
+		})
+	}
+}
+
+func TestRecycleQueueBackoff(t *testing.T) {
+	q := &recycleQueue{Backoff: time.Second, MaxBackoff: time.Second * 5}
+	for attempts, desired := range []time.Duration{time.Second, time.Second, time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5} {
+		if backoff := q.backoff(attempts); backoff != desired {
+			t.Errorf("✗ expected backoff %s after %d attempts, got %s", desired, attempts, backoff)
+		}
+	}
+	if isPermanent(errors.New("connection refused")) {
+		t.Errorf("✗ expected connection errors to be retried")
+	}
+}
+
+func newQueueTestItem(name string) *api.RecycleItem {
+	return api.NewRecycleItem(&api.RecycledObject{Version: "v1", Kind: "ConfigMap", Resource: "configmaps", Namespace: "dev", Name: name})
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return
+		}
+	}
+	t.Fatalf("✗ timed out waiting for condition")
+}
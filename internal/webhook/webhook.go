@@ -18,55 +18,151 @@ package webhook
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/go-logr/logr"
 	"github.com/ketches/kube-recycle-bin/internal/api"
 	krbclient "github.com/ketches/kube-recycle-bin/internal/client"
 	"github.com/ketches/kube-recycle-bin/internal/consts"
+	"github.com/ketches/kube-recycle-bin/internal/storage"
 	"github.com/ketches/kube-recycle-bin/pkg/kube"
 	"github.com/ketches/kube-recycle-bin/pkg/tlog"
 	"github.com/ketches/kube-recycle-bin/pkg/util"
 	admissionv1 "k8s.io/api/admission/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/client-go/util/retry"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+const serviceAccountUsernamePrefix = "system:serviceaccount:"
+
+// exclusions are never recycled, even if the webhook configuration still
+// intercepts them.
+var exclusions = api.BuiltinExclusions
+
+// recycleItems queues the RecycleItems of deleted objects, they are created
+// right away if it is nil.
+var recycleItems *recycleQueue
+
 func init() {
 	log.SetLogger(logr.New(log.NullLogSink{}))
 }
 
 // Run starts the webhook server.
 func Run() {
+	var encoding, maxObjectSize, overflowPolicy string
+	flag.StringVar(&encoding, "encoding", payload.Encoding, "Encoding of recycled objects. One of: none|gzip")
+	flag.StringVar(&maxObjectSize, "max-object-size", "768Ki",
+		"Max size of an encoded recycled object stored in a RecycleItem, such as 768Ki. Zero for no limit.")
+	flag.StringVar(&overflowPolicy, "overflow-policy", string(payload.OverflowPolicy),
+		"What happens to recycled objects larger than the max object size. "+
+			"Reject rejects their deletion, Truncate keeps only their metadata and Offload stores them in ConfigMaps. "+
+			"Ignored if a storage backend is configured in the krb-storage Secret. One of: Reject|Truncate|Offload")
+	var encrypt bool
+	flag.BoolVar(&encrypt, "encryption", true,
+		"Encrypt recycled objects of the kinds configured in the krb-encryption Secret, Secrets by default.")
+	var certDir string
+	var certReloadInterval time.Duration
+	flag.StringVar(&certDir, "cert-dir", "",
+		"Directory with the tls.crt and tls.key serving certificate, such as a mounted Secret issued by cert-manager, "+
+			"which is reloaded when it changes. The certificate is taken from the krb-webhook-tls Secret if empty.")
+	flag.DurationVar(&certReloadInterval, "cert-reload-interval", time.Minute,
+		"How often to reload the serving certificate, which is rotated by krb-controller or whoever provides it.")
+	queue := &recycleQueue{Create: createRecycleItem, Discard: discardPayload}
+	flag.StringVar(&queue.Dir, "spool-dir", "/var/spool/krb-webhook",
+		"Directory the recycle queue persists deleted objects in until their RecycleItems are created, "+
+			"failed ones are moved to its dead subdirectory. RecycleItems are created within the admission request if empty.")
+	flag.IntVar(&queue.Workers, "queue-workers", 4, "Number of workers creating RecycleItems from the recycle queue.")
+	flag.IntVar(&queue.MaxAttempts, "queue-max-attempts", 10,
+		"Max attempts to create a RecycleItem from the recycle queue before it is moved to the dead letters.")
+	flag.DurationVar(&queue.Backoff, "queue-backoff", time.Second,
+		"Delay before retrying to create a RecycleItem from the recycle queue, doubled after every failed attempt.")
+	flag.DurationVar(&queue.MaxBackoff, "queue-max-backoff", time.Minute*5,
+		"Max delay before retrying to create a RecycleItem from the recycle queue.")
+	flag.BoolVar(&queue.DiscardDeadLetters, "discard-dead-letters", false,
+		"Discard the dead letters of the recycle queue and delete their stored payloads on start.")
+	var shutdownTimeout time.Duration
+	flag.DurationVar(&shutdownTimeout, "shutdown-timeout", time.Second*25,
+		"How long to wait for pending requests and the recycle queue to drain on termination, "+
+			"shorter than the termination grace period of the pod.")
+	flag.Parse()
+
+	var err error
+	if exclusions, err = api.LoadExclusions(context.Background(), kube.Client()); err != nil {
+		tlog.Fatalf("✗ failed to load exclusions: %v", err)
+	}
+	if payload.Encoding, err = api.ParseEncoding(encoding); err != nil {
+		tlog.Fatalf("✗ %v", err)
+	}
+	size, err := resource.ParseQuantity(maxObjectSize)
+	if err != nil {
+		tlog.Fatalf("✗ invalid max object size %q: %v", maxObjectSize, err)
+	}
+	payload.MaxObjectSize = int(size.Value())
+	if payload.OverflowPolicy, err = ParseOverflowPolicy(overflowPolicy); err != nil {
+		tlog.Fatalf("✗ %v", err)
+	}
+
 	tlog.Info("» starting admission webhook server...")
 
-	ensureTLSFiles()
+	payload.setupStorage(context.Background())
+	if encrypt {
+		payload.setupEncryption(context.Background())
+	}
+	setupCertificate(context.Background(), certDir, certReloadInterval)
+	setupPolicyCache(context.Background())
+	if queue.Dir != "" {
+		if queue.Workers < 1 || queue.MaxAttempts < 1 || queue.Backoff <= 0 || queue.MaxBackoff < queue.Backoff {
+			tlog.Fatalf("✗ invalid recycle queue options, workers and max attempts must be positive and backoff must be positive and at most max backoff")
+		}
+		if err := queue.Start(context.Background()); err != nil {
+			tlog.Fatalf("✗ failed to start recycle queue: %v", err)
+		}
+		recycleItems = queue
+	}
 	http.HandleFunc(consts.WebhookServicePath, recycleDeleteObjects)
+	http.HandleFunc(consts.WebhookPolicyPath, validateRecyclePolicies)
+	http.Handle(consts.WebhookMetricsPath, metricsHandler)
+	http.HandleFunc(consts.WebhookPayloadsPath, servePayloads)
 
-	if err := http.ListenAndServeTLS(":443", consts.WebhookServiceTLSCertFile, consts.WebhookServiceTLSKeyFile, nil); err != nil {
-		tlog.Fatalf("✗ failed to listen and serve admission webhook: %v", err)
+	server := &http.Server{
+		Addr:      ":443",
+		TLSConfig: &tls.Config{GetCertificate: getCertificate},
 	}
-}
-
-func ensureTLSFiles() {
-	cert, key := FetchWebhookCertAndKey()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := server.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+			tlog.Fatalf("✗ failed to listen and serve admission webhook: %v", err)
+		}
+	}()
+	<-ctx.Done()
 
-	err := os.WriteFile(consts.WebhookServiceTLSCertFile, cert, 0644)
-	if err != nil {
-		tlog.Fatalf("✗ failed to write cert file: %v", err)
+	// Deleted objects admitted before the termination are recycled before
+	// the webhook exits, as far as the grace period allows.
+	tlog.Info("» shutting down admission webhook server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		tlog.Warnf("✗ failed to shut down admission webhook server: %v", err)
 	}
-	err = os.WriteFile(consts.WebhookServiceTLSKeyFile, key, 0644)
-	if err != nil {
-		tlog.Fatalf("✗ failed to write key file: %v", err)
+	if recycleItems != nil {
+		recycleItems.Stop(shutdownCtx)
 	}
-
-	tlog.Infof("✓ cert and key files generated.")
 }
 
 // recycleDeleteObjects webhook handler for recycling deleted objects.
@@ -81,27 +177,161 @@ func recycleDeleteObjects(w http.ResponseWriter, r *http.Request) {
 
 	request := review.Request
 
+	// Dry-run deletions don't delete anything, so there is nothing to recycle.
+	if request.DryRun != nil && *request.DryRun {
+		gr := schema.GroupResource{Group: request.Resource.Group, Resource: request.Resource.Resource}
+		tlog.Infof("» skip recycling dry-run deletion of [%s: %s]", gr.String(), requestKey(request))
+		dryRunSkippedTotal.WithLabelValues(gr.String()).Inc()
+		response(w, review)
+		return
+	}
+
+	if gr := (schema.GroupResource{Group: request.Resource.Group, Resource: request.Resource.Resource}); exclusions.ExcludesResource(gr) || exclusions.ExcludesNamespace(request.Namespace) {
+		tlog.Infof("» skip recycling excluded object [%s: %s]", gr.String(), requestKey(request))
+		response(w, review)
+		return
+	}
+
 	// Create RecycleItem to recycle the deleted object.
 	recycledObj := buildRecycledObject(request)
 	if recycledObj != nil {
-		tlog.Infof("» prepare to recycle deleted object [%s: %s]", recycledObj.GroupResource().String(), recycledObj.Key())
-		recycleItem := api.NewRecycleItem(recycledObj)
-		if err := retry.OnError(retry.DefaultRetry, k8serrors.IsAlreadyExists, func() error {
-			if err := krbclient.RecycleItem().Create(context.Background(), recycleItem, client.CreateOptions{}); err != nil {
-				return err
+		objectMeta, err := recycledObj.ObjectMeta()
+		if err != nil {
+			tlog.Warnf("✗ failed to decode metadata of deleted object [%s: %s]: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+			objectMeta = &metav1.ObjectMeta{}
+		}
+		if objectMeta.Annotations[api.SkipAnnotation] == "true" {
+			tlog.Infof("» skip recycling deleted object [%s: %s] annotated with %s", recycledObj.GroupResource().String(), recycledObj.Key(), api.SkipAnnotation)
+			response(w, review)
+			return
+		}
+		key := deletionKey(recycledObj, objectMeta)
+		if deletions.Seen(key, time.Now()) {
+			tlog.Infof("» skip recycling deleted object [%s: %s] recycled by another webhook", recycledObj.GroupResource().String(), recycledObj.Key())
+			duplicateDeletionsTotal.WithLabelValues(recycledObj.GroupResource().String()).Inc()
+			response(w, review)
+			return
+		}
+		// A deletion that is denied, or allowed without a RecycleItem, is
+		// recycled again when it is retried.
+		recycled := false
+		defer func() {
+			if !recycled {
+				deletions.Forget(key)
 			}
+		}()
 
-			tlog.Infof("✓ recycle deleted object [%s: %s] done.", recycledObj.GroupResource().String(), recycledObj.Key())
+		tlog.Infof("» prepare to recycle deleted object [%s: %s]", recycledObj.GroupResource().String(), recycledObj.Key())
+		policy, err := matchRecyclePolicy(context.Background(), policies, recycledObj, objectMeta)
+		// Redacted values must never be persisted, and the object must not
+		// be deleted without being recycled, so the deletion is denied if
+		// the policy with its redact rules can't be matched or they can't be
+		// removed.
+		if err != nil {
+			tlog.Errorf("✗ reject deletion of object [%s: %s]: failed to match recycle policy: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+			validationResponse(w, review, nil, fmt.Errorf("deleted object can't be recycled: failed to match recycle policy: %w", err))
+			return
+		}
+		if policy != nil {
+			if err := recycledObj.Redact(policy.Redact); err != nil {
+				tlog.Errorf("✗ reject deletion of object [%s: %s]: failed to redact: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+				redactFailuresTotal.WithLabelValues(recycledObj.GroupResource().String()).Inc()
+				validationResponse(w, review, nil, fmt.Errorf("deleted object can't be recycled: failed to redact: %w", err))
+				return
+			}
+		}
+		backend, err := payload.encode(recycledObj)
+		if errors.Is(err, errObjectTooLarge) {
+			tlog.Errorf("✗ reject deletion of object [%s: %s]: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+			validationResponse(w, review, nil, fmt.Errorf("deleted object can't be recycled: %w", err))
+			return
+		}
+		if err != nil {
+			tlog.Errorf("✗ failed to encode deleted object [%s: %s]: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+			response(w, review)
+			return
+		}
 
-			return nil
+		recycleItem := api.NewRecycleItem(recycledObj)
+		recycleItem.Deletion = buildDeletionInfo(request)
+		if policy != nil {
+			recycleItem.ApplyPolicy(policy)
+		}
+		if backend != nil {
+			if err := store(backend, recycleItem); err != nil {
+				tlog.Errorf("✗ failed to store deleted object [%s: %s] in the %s backend: %v", recycledObj.GroupResource().String(), recycledObj.Key(), backend.Name(), err)
+				response(w, review)
+				return
+			}
+		}
+		if recycleItems != nil {
+			err := recycleItems.Enqueue(recycleItem)
+			if err == nil {
+				tlog.Infof("✓ queue deleted object [%s: %s] for recycling done.", recycledObj.GroupResource().String(), recycledObj.Key())
+				recycled = true
+				response(w, review)
+				return
+			}
+			tlog.Errorf("✗ failed to queue deleted object [%s: %s], recycling it right away: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+		}
+		// RecycleItems created right away are retried on name collisions
+		if err := retry.OnError(retry.DefaultRetry, k8serrors.IsAlreadyExists, func() error {
+			return createRecycleItem(context.Background(), recycleItem)
 		}); err != nil {
 			tlog.Errorf("✗ failed to recycle deleted object [%s: %s]: %v", recycledObj.GroupResource().String(), recycledObj.Key(), err)
+			if backend != nil {
+				if err := backend.Delete(context.Background(), recycleItem.Object.Ref.Key); err != nil {
+					tlog.Warnf("✗ failed to delete payload [%s] without RecycleItem: %v", recycleItem.Object.Ref.Key, err)
+				}
+			}
+		} else {
+			recycled = true
 		}
 	}
 
 	response(w, review)
 }
 
+// createRecycleItem creates the RecycleItem once, which krb-controller
+// counts on the status of its RecyclePolicy.
+func createRecycleItem(ctx context.Context, recycleItem *api.RecycleItem) error {
+	if err := krbclient.RecycleItem().Create(ctx, recycleItem, client.CreateOptions{}); err != nil {
+		return err
+	}
+	tlog.Infof("✓ recycle deleted object [%s: %s] done.", recycleItem.Object.GroupResource().String(), recycleItem.Object.Key())
+	return nil
+}
+
+// store moves the payload of the RecycleItem to the storage backend, before
+// the RecycleItem referencing it is created.
+func store(backend storage.Backend, recycleItem *api.RecycleItem) error {
+	data := recycleItem.Offload(backend.Name())
+	if err := backend.Put(context.Background(), recycleItem.Object.Ref.Key, data); err != nil {
+		storageErrorsTotal.WithLabelValues(backend.Name(), "put").Inc()
+		return err
+	}
+	return nil
+}
+
+// discardPayload deletes the payload stored for the RecycleItem, which is
+// never created.
+func discardPayload(ctx context.Context, recycleItem *api.RecycleItem) error {
+	ref := recycleItem.PayloadRef()
+	if ref == nil {
+		return nil
+	}
+	for _, backend := range []storage.Backend{payload.Backend, payload.OverflowBackend} {
+		if backend != nil && backend.Name() == ref.Backend {
+			if err := backend.Delete(ctx, ref.Key); err != nil {
+				storageErrorsTotal.WithLabelValues(backend.Name(), "delete").Inc()
+				return err
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("%s backend isn't configured", ref.Backend)
+}
+
 // parseRequest parses the request of the admission webhook.
 func parseRequest(r *http.Request) (*admissionv1.AdmissionReview, error) {
 	var (
@@ -116,6 +346,11 @@ func parseRequest(r *http.Request) (*admissionv1.AdmissionReview, error) {
 	return &request, nil
 }
 
+// requestKey returns the namespace/name key of the object in the request.
+func requestKey(request *admissionv1.AdmissionRequest) string {
+	return api.NamespacedKey(request.Namespace, request.Name)
+}
+
 // buildRecycledObject constructs api.RecycledObject from the request
 func buildRecycledObject(request *admissionv1.AdmissionRequest) *api.RecycledObject {
 	namespaced, err := kube.IsResourceNamespaced(schema.GroupVersionResource{
@@ -138,6 +373,31 @@ func buildRecycledObject(request *admissionv1.AdmissionRequest) *api.RecycledObj
 	}
 }
 
+// buildDeletionInfo constructs api.DeletionInfo from the request
+func buildDeletionInfo(request *admissionv1.AdmissionRequest) *api.DeletionInfo {
+	result := &api.DeletionInfo{
+		Username:     request.UserInfo.Username,
+		UserUID:      request.UserInfo.UID,
+		Groups:       request.UserInfo.Groups,
+		AdmissionUID: request.UID,
+		DeletedAt:    metav1.Now(),
+	}
+	if sa, ok := strings.CutPrefix(request.UserInfo.Username, serviceAccountUsernamePrefix); ok {
+		result.ServiceAccount = strings.Replace(sa, ":", "/", 1)
+	}
+
+	if len(request.Options.Raw) > 0 {
+		var options metav1.DeleteOptions
+		if err := json.Unmarshal(request.Options.Raw, &options); err != nil {
+			tlog.Warnf("✗ failed to decode delete options: %v", err)
+		} else {
+			result.PropagationPolicy = options.PropagationPolicy
+			result.GracePeriodSeconds = options.GracePeriodSeconds
+		}
+	}
+	return result
+}
+
 // response sends the response to the admission webhook.
 func response(w http.ResponseWriter, request *admissionv1.AdmissionReview) {
 	response := &admissionv1.AdmissionReview{
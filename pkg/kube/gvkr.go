@@ -20,7 +20,9 @@ import (
 	"fmt"
 	"slices"
 
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/discovery"
 	"k8s.io/client-go/restmapper"
 )
 
@@ -150,17 +152,67 @@ func GetPreferredGroupVersionResourceFor(resource string) (*schema.GroupVersionR
 
 // IsResourceNamespaced checks if the given GroupVersionResource is namespaced.
 func IsResourceNamespaced(gvr schema.GroupVersionResource) (bool, error) {
+	apiResource, err := GetAPIResource(gvr)
+	if err != nil {
+		return false, fmt.Errorf("can not assert if resource %s is namespaced: %w", gvr.GroupResource().String(), err)
+	}
+	return apiResource.Namespaced, nil
+}
+
+// GetAPIResource returns the APIResource of the given GroupVersionResource.
+func GetAPIResource(gvr schema.GroupVersionResource) (*metav1.APIResource, error) {
 	discoveryClient := DiscoveryClient()
 
 	apiResourceList, err := discoveryClient.ServerResourcesForGroupVersion(gvr.GroupVersion().String())
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	for _, apiResource := range apiResourceList.APIResources {
 		if apiResource.Name == gvr.Resource {
-			return apiResource.Namespaced, nil
+			return &apiResource, nil
+		}
+	}
+	return nil, fmt.Errorf("can not find resource %s", gvr.String())
+}
+
+// GetPreferredAPIResources returns the APIResources of the preferred versions of all resources with their
+// group and version set, groups that fail discovery are skipped.
+func GetPreferredAPIResources() ([]metav1.APIResource, error) {
+	discoveryClient := DiscoveryClient()
+
+	apiResourceLists, err := discoveryClient.ServerPreferredResources()
+	if err != nil && !discovery.IsGroupDiscoveryFailedError(err) {
+		return nil, err
+	}
+
+	var result []metav1.APIResource
+	for _, resourceList := range apiResourceLists {
+		gv, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, res := range resourceList.APIResources {
+			res.Group, res.Version = gv.Group, gv.Version
+			result = append(result, res)
+		}
+	}
+	return result, nil
+}
+
+// GetPreferredAPIResource returns the APIResource of the preferred version of the given GroupResource.
+// resource name must be the plural name.
+func GetPreferredAPIResource(gr schema.GroupResource) (*metav1.APIResource, error) {
+	resources, err := GetPreferredAPIResources()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, res := range resources {
+		if res.Group == gr.Group && res.Name == gr.Resource {
+			return &res, nil
 		}
 	}
-	return false, fmt.Errorf("can not assert if resource %s is namespaced", gvr.GroupResource().String())
+	return nil, fmt.Errorf("can not find resource %s", gr.String())
 }
@@ -0,0 +1,206 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// diffOp is a line of a diff, kind is one of ' ', '-' and '+'. from and to are
+// the indexes of the line in the old and new text.
+type diffOp struct {
+	kind     byte
+	text     string
+	from, to int
+}
+
+// UnifiedDiff returns the unified diff from the old text to the new text with
+// the given lines of context, or an empty string if they are equal.
+func UnifiedDiff(fromName, toName, from, to string, context int) string {
+	ops := diffLines(splitLines(from), splitLines(to))
+
+	// Hunks are ranges of ops around changes, merged when their context overlaps.
+	var hunks [][2]int
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := max(0, i-context), min(len(ops), i+context+1)
+		if n := len(hunks); n > 0 && start <= hunks[n-1][1] {
+			hunks[n-1][1] = max(hunks[n-1][1], end)
+		} else {
+			hunks = append(hunks, [2]int{start, end})
+		}
+	}
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+	for _, hunk := range hunks {
+		hunkOps := ops[hunk[0]:hunk[1]]
+		var fromCount, toCount int
+		for _, op := range hunkOps {
+			if op.kind != '+' {
+				fromCount++
+			}
+			if op.kind != '-' {
+				toCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(hunkOps[0].from, fromCount), hunkRange(hunkOps[0].to, toCount))
+		for _, op := range hunkOps {
+			sb.WriteByte(op.kind)
+			sb.WriteString(op.text)
+			sb.WriteByte('\n')
+		}
+	}
+	return sb.String()
+}
+
+// hunkRange formats the range of a hunk, an empty range refers to the line
+// before it.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(s string) []string {
+	s = strings.TrimSuffix(s, "\n")
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, "\n")
+}
+
+// diffLines diffs the lines with the linear space variant of the Myers
+// algorithm, which finds a shortest edit script in O((N+M)D) time and O(N+M)
+// space, so large manifests can be diffed as well.
+func diffLines(a, b []string) []diffOp {
+	d := &differ{a: a, b: b}
+	d.compare(0, len(a), 0, len(b))
+	return d.ops
+}
+
+type differ struct {
+	a, b []string
+	ops  []diffOp
+}
+
+// compare appends the ops turning a[aLo:aHi] into b[bLo:bHi].
+func (d *differ) compare(aLo, aHi, bLo, bHi int) {
+	// Common prefixes and suffixes are kept as they are.
+	for aLo < aHi && bLo < bHi && d.a[aLo] == d.b[bLo] {
+		d.ops = append(d.ops, diffOp{kind: ' ', text: d.a[aLo], from: aLo, to: bLo})
+		aLo++
+		bLo++
+	}
+	var suffix int
+	for aLo < aHi-suffix && bLo < bHi-suffix && d.a[aHi-suffix-1] == d.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi, bHi = aHi-suffix, bHi-suffix
+
+	switch {
+	case aLo == aHi:
+		for j := bLo; j < bHi; j++ {
+			d.ops = append(d.ops, diffOp{kind: '+', text: d.b[j], from: aLo, to: j})
+		}
+	case bLo == bHi:
+		for i := aLo; i < aHi; i++ {
+			d.ops = append(d.ops, diffOp{kind: '-', text: d.a[i], from: i, to: bLo})
+		}
+	default:
+		// Both ranges are non-empty and differ at both ends, so at least two
+		// edits are needed and the middle snake splits them into smaller
+		// problems.
+		x, y, u, v := d.middleSnake(aLo, aHi, bLo, bHi)
+		d.compare(aLo, x, bLo, y)
+		for ; x < u; x, y = x+1, y+1 {
+			d.ops = append(d.ops, diffOp{kind: ' ', text: d.a[x], from: x, to: y})
+		}
+		d.compare(u, aHi, v, bHi)
+	}
+
+	for i := 0; i < suffix; i++ {
+		d.ops = append(d.ops, diffOp{kind: ' ', text: d.a[aHi+i], from: aHi + i, to: bHi + i})
+	}
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the
+// middle of a shortest edit script from a[aLo:aHi] to b[bLo:bHi], found by
+// searching forward from the start and backward from the end until the paths
+// overlap.
+func (d *differ) middleSnake(aLo, aHi, bLo, bHi int) (x, y, u, v int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	// forward[k] is the furthest x on diagonal k = x - y from the start, and
+	// backward[k] the furthest distance from the end on diagonal k of the
+	// reversed ranges, where diagonal k of the reversed ranges is diagonal
+	// delta - k of the ranges.
+	offset := limit + 1
+	forward, backward := make([]int, 2*offset+1), make([]int, 2*offset+1)
+
+	for e := 0; e <= limit; e++ {
+		for k := -e; k <= e; k += 2 {
+			var fx int
+			if k == -e || (k != e && forward[offset+k-1] < forward[offset+k+1]) {
+				fx = forward[offset+k+1]
+			} else {
+				fx = forward[offset+k-1] + 1
+			}
+			fy := fx - k
+			sx, sy := fx, fy
+			for fx < n && fy < m && d.a[aLo+fx] == d.b[bLo+fy] {
+				fx, fy = fx+1, fy+1
+			}
+			forward[offset+k] = fx
+			if r := delta - k; odd && r >= -(e-1) && r <= e-1 && fx+backward[offset+r] >= n {
+				return aLo + sx, bLo + sy, aLo + fx, bLo + fy
+			}
+		}
+		for k := -e; k <= e; k += 2 {
+			var bx int
+			if k == -e || (k != e && backward[offset+k-1] < backward[offset+k+1]) {
+				bx = backward[offset+k+1]
+			} else {
+				bx = backward[offset+k-1] + 1
+			}
+			by := bx - k
+			sx, sy := bx, by
+			for bx < n && by < m && d.a[aHi-bx-1] == d.b[bHi-by-1] {
+				bx, by = bx+1, by+1
+			}
+			backward[offset+k] = bx
+			if f := delta - k; !odd && f >= -e && f <= e && forward[offset+f]+bx >= n {
+				return aHi - bx, bHi - by, aHi - sx, bHi - sy
+			}
+		}
+	}
+	// The paths always overlap within the limit, replacing the whole range is
+	// still a valid edit script otherwise.
+	return aHi, bLo, aHi, bLo
+}
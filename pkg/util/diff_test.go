@@ -0,0 +1,137 @@
+/*
+Copyright 2025 The Ketches Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package util
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	testdata := []struct {
+		name    string
+		from    string
+		to      string
+		desired string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+		},
+		{
+			name:    "from-empty",
+			to:      "a\nb\n",
+			desired: "--- from\n+++ to\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:    "change",
+			from:    "a\nb\nc\nd\ne\nf\ng\nh\n",
+			to:      "a\nb\nc\nd\nE\nf\ng\nh\n",
+			desired: "--- from\n+++ to\n@@ -3,5 +3,5 @@\n c\n d\n-e\n+E\n f\n g\n",
+		},
+		{
+			name:    "separate-hunks",
+			from:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			to:      "0\n2\n3\n4\n5\n6\n7\n8\n",
+			desired: "--- from\n+++ to\n@@ -1,3 +1,3 @@\n-1\n+0\n 2\n 3\n@@ -7,3 +7,2 @@\n 7\n 8\n-9\n",
+		},
+	}
+
+	for _, tt := range testdata {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff("from", "to", tt.from, tt.to, 2)
+			if got != tt.desired {
+				t.Errorf("✗ expected:\n%s\ngot:\n%s", tt.desired, got)
+			}
+		})
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcs is the length of the longest common subsequence, which a shortest
+	// edit script keeps.
+	lcs := func(a, b []string) int {
+		prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+		for i := range a {
+			for j := range b {
+				if a[i] == b[j] {
+					cur[j+1] = prev[j] + 1
+				} else {
+					cur[j+1] = max(prev[j+1], cur[j])
+				}
+			}
+			prev, cur = cur, prev
+		}
+		return prev[len(b)]
+	}
+	random := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		result := make([]string, random.Intn(12))
+		for i := range result {
+			result[i] = string(rune('a' + random.Intn(4)))
+		}
+		return result
+	}
+
+	for i := 0; i < 500; i++ {
+		a, b := lines(), lines()
+		var from, to []string
+		var kept int
+		for _, op := range diffLines(a, b) {
+			if op.kind != '+' {
+				from = append(from, op.text)
+			}
+			if op.kind != '-' {
+				to = append(to, op.text)
+			}
+			if op.kind == ' ' {
+				kept++
+			}
+		}
+		if strings.Join(from, "") != strings.Join(a, "") || strings.Join(to, "") != strings.Join(b, "") {
+			t.Fatalf("✗ expected diff from %v to %v, got from %v to %v", a, b, from, to)
+		}
+		if desired := lcs(a, b); kept != desired {
+			t.Fatalf("✗ expected %d kept lines diffing %v and %v, got %d", desired, a, b, kept)
+		}
+	}
+}
+
+func TestUnifiedDiffLarge(t *testing.T) {
+	var from, to strings.Builder
+	for i := 0; i < 20000; i++ {
+		fmt.Fprintf(&from, "line %d\n", i)
+		if i%5000 == 0 {
+			fmt.Fprintf(&to, "changed %d\n", i)
+		} else {
+			fmt.Fprintf(&to, "line %d\n", i)
+		}
+	}
+
+	start := time.Now()
+	got := UnifiedDiff("from", "to", from.String(), to.String(), 0)
+	if n := strings.Count(got, "@@ -"); n != 4 {
+		t.Errorf("✗ expected 4 hunks, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second*5 {
+		t.Errorf("✗ expected large diff within 5s, took %v", elapsed)
+	}
+}